@@ -0,0 +1,121 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"ttrss"
+)
+
+// headlinePageSize is how many headlines eachHeadline asks for at a time.
+// The server caps it at 200 anyway.
+const headlinePageSize = 200
+
+// parseArticleIDs parses each of args as an article ID, except that "-"
+// stands for those read from stdin by readArticleIDs.
+func parseArticleIDs(args []string) (ids []int, err error) {
+	for _, arg := range args {
+		if arg == "-" {
+			read, err := readArticleIDs(os.Stdin)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, read...)
+			continue
+		}
+		id, convErr := strconv.Atoi(arg)
+		if convErr != nil || id <= 0 {
+			return nil, fmt.Errorf("not an article ID: %q", arg)
+		}
+		ids = append(ids, id)
+	}
+	return
+}
+
+// readArticleIDs reads an article ID from the start of each line read from
+// r, skipping blank lines. Whatever follows the ID on a line is ignored, so
+// that the output of ls --articles will do.
+func readArticleIDs(r io.Reader) (ids []int, err error) {
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		fields := strings.Fields(lines.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		id, convErr := strconv.Atoi(fields[0])
+		if convErr != nil || id <= 0 {
+			return nil, fmt.Errorf("not an article ID: %q", fields[0])
+		}
+		ids = append(ids, id)
+	}
+	if err = lines.Err(); err != nil {
+		err = fmt.Errorf("reading stdin: %v", err)
+	}
+	return
+}
+
+// latestHeadline returns the newest article in item, or nil if there are
+// none.
+func latestHeadline(item *ttrss.FeedTreeItem) (*ttrss.Headline, error) {
+	req := headlinesRequestFor(item)
+	req.Limit = 1
+	headlines, err := tt.GetHeadlines(req)
+	if err != nil || len(headlines) == 0 {
+		return nil, err
+	}
+	return &headlines[0], nil
+}
+
+// eachHeadline calls fn on every article req selects, newest first, a page
+// at a time.
+func eachHeadline(req ttrss.HeadlinesRequest, fn func(ttrss.Headline)) error {
+	return eachHeadlineWhile(req, func(h ttrss.Headline) bool {
+		fn(h)
+		return true
+	})
+}
+
+// eachHeadlineWhile is eachHeadline, but stops, fetching no more pages, as
+// soon as fn returns false.
+func eachHeadlineWhile(req ttrss.HeadlinesRequest,
+	fn func(ttrss.Headline) bool) error {
+	req.Limit = headlinePageSize
+	for {
+		page, err := tt.GetHeadlines(req)
+		if err != nil {
+			return err
+		}
+		for _, h := range page {
+			if !fn(h) {
+				return nil
+			}
+		}
+		if len(page) < req.Limit {
+			return nil
+		}
+		req.Skip += len(page)
+	}
+}
+
+// updateArticles sets field on the articles with ids as mode says, and logs
+// the change to each as made by op.
+func updateArticles(op string, ids []int, field ttrss.ArticleField,
+	mode ttrss.UpdateMode) error {
+	updated, err := tt.UpdateArticle(ids, field, mode, "")
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected += updated
+	}
+	for _, id := range ids {
+		logChange(ChangelogEntry{Op: op, ID: id, Result: result})
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+	"ttrss"
+)
+
+// Just enough of RFC 4287 to describe a list of articles.
+type atomFeed struct {
+	XMLName   xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title     string      `xml:"title"`
+	ID        string      `xml:"id"`
+	Updated   string      `xml:"updated"`
+	Generator string      `xml:"generator"`
+	Entries   []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title   string       `xml:"title"`
+	ID      string       `xml:"id"`
+	Updated string       `xml:"updated"`
+	Link    *atomLink    `xml:"link,omitempty"`
+	Author  atomPerson   `xml:"author"`
+	Content *atomContent `xml:"content,omitempty"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// tagURI makes an Atom ID out of specific, unique to the server at --addr.
+// See RFC 4151.
+func tagURI(specific string) string {
+	host := flAddr
+	if u, err := url.Parse(flAddr); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	return "tag:" + host + ",2013:" + specific
+}
+
+// writeAtom writes headlines to w as an Atom feed titled title.
+// id distinguishes this feed from others made from the same server.
+func writeAtom(w io.Writer, title, id string,
+	headlines []ttrss.Headline) error {
+	feed := atomFeed{
+		Title:     title,
+		ID:        tagURI(id),
+		Generator: "ttrss-tool",
+	}
+
+	var updated time.Time
+	for _, h := range headlines {
+		if h.Updated.After(updated) {
+			updated = h.Updated
+		}
+
+		// Atom insists on an author; the feed is the next best thing.
+		author := h.Author
+		if author == "" {
+			author = h.FeedTitle
+		}
+		entry := atomEntry{
+			Title:   h.Title,
+			ID:      tagURI("article/" + strconv.Itoa(h.ID)),
+			Updated: h.Updated.UTC().Format(time.RFC3339),
+			Author:  atomPerson{author},
+		}
+		if h.Link != "" {
+			entry.Link = &atomLink{"alternate", h.Link}
+		}
+		if h.Content != "" {
+			entry.Content = &atomContent{"html", h.Content}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	feed.Updated = updated.UTC().Format(time.RFC3339)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,95 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+	"ttrss"
+)
+
+func TestWriteAtom(t *testing.T) {
+	defer func(addr string) { flAddr = addr }(flAddr)
+	flAddr = "https://rss.example.com/tt-rss"
+
+	older := time.Date(2013, 5, 1, 12, 0, 0, 0, time.UTC)
+	newer := time.Date(2013, 5, 2, 8, 30, 0, 0, time.FixedZone("", 3600))
+	headlines := []ttrss.Headline{
+		{ID: 7, Title: "Fish & chips", Link: "http://a.example/7",
+			Author: "Alice", FeedTitle: "Food", Updated: older,
+			Content: "<p>Vinegar</p>"},
+		{ID: 8, Title: "Untitled", FeedTitle: "Weather",
+			Updated: newer},
+	}
+
+	var b bytes.Buffer
+	if err := writeAtom(&b, "Unread", "unread", headlines); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(b.String(), xml.Header) {
+		t.Errorf("no XML declaration:\n%s", b.String())
+	}
+
+	var got atomFeed
+	if err := xml.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("output does not parse: %v\n%s", err, b.String())
+	}
+	if got.Title != "Unread" ||
+		got.ID != "tag:rss.example.com,2013:unread" ||
+		got.Generator != "ttrss-tool" {
+		t.Errorf("feed = %q %q %q", got.Title, got.ID, got.Generator)
+	}
+	if want := "2013-05-02T07:30:00Z"; got.Updated != want {
+		t.Errorf("feed updated %q, want the newest entry's %q",
+			got.Updated, want)
+	}
+	if len(got.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got.Entries))
+	}
+
+	e := got.Entries[0]
+	if e.Title != "Fish & chips" ||
+		e.ID != "tag:rss.example.com,2013:article/7" ||
+		e.Updated != "2013-05-01T12:00:00Z" ||
+		e.Author.Name != "Alice" {
+		t.Errorf("first entry = %+v", e)
+	}
+	if e.Link == nil || *e.Link != (atomLink{"alternate",
+		"http://a.example/7"}) {
+		t.Errorf("first entry link = %+v", e.Link)
+	}
+	if e.Content == nil || *e.Content != (atomContent{"html",
+		"<p>Vinegar</p>"}) {
+		t.Errorf("first entry content = %+v", e.Content)
+	}
+
+	e = got.Entries[1]
+	if e.Author.Name != "Weather" {
+		t.Errorf("author %q, want the feed title to stand in",
+			e.Author.Name)
+	}
+	if e.Link != nil || e.Content != nil {
+		t.Errorf("second entry has link %+v, content %+v, want neither",
+			e.Link, e.Content)
+	}
+}
+
+func TestWriteAtomEmpty(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeAtom(&b, "Nothing", "none", nil); err != nil {
+		t.Fatal(err)
+	}
+	var got atomFeed
+	if err := xml.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("output does not parse: %v\n%s", err, b.String())
+	}
+	if len(got.Entries) != 0 {
+		t.Errorf("got %d entries, want none", len(got.Entries))
+	}
+	if _, err := time.Parse(time.RFC3339, got.Updated); err != nil {
+		t.Errorf("feed updated %q: %v", got.Updated, err)
+	}
+}
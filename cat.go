@@ -0,0 +1,237 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"ttrss"
+)
+
+type Cat struct {
+	flHelp   bool
+	flFull   bool
+	flUnread bool
+	flSince  ageValue
+	flAtom   bool
+	flJSON   bool
+	flFormat string
+	flOutput string
+	flags    flag.FlagSet
+
+	// format is the parsed --format template, if any.
+	format *template.Template
+}
+
+func (cat *Cat) Init() {
+	cat.flags.Init("cat", flag.PanicOnError)
+
+	cat.flags.BoolVar(&cat.flHelp, "h", false, "help")
+	cat.flags.BoolVar(&cat.flHelp, "help", false, "help")
+
+	fullUsage := "follow each article with its content, as plain text"
+	cat.flags.BoolVar(&cat.flFull, "f", false, fullUsage)
+	cat.flags.BoolVar(&cat.flFull, "full", false, fullUsage)
+
+	cat.flags.BoolVar(&cat.flUnread, "unread", false,
+		"only print unread articles")
+	cat.flags.Var(&cat.flSince, "since",
+		"only print articles updated in the last `AGE`, like 48h or 2d")
+	cat.flags.BoolVar(&cat.flAtom, "atom", false,
+		"print the articles from every catpath as one Atom feed")
+	cat.flags.BoolVar(&cat.flJSON, "jsonfeed", false,
+		"print the articles from every catpath as one JSON Feed")
+	cat.flags.StringVar(&cat.flFormat, "format", "",
+		"print each article using the Go text/template `TEMPLATE`")
+	cat.flags.StringVar(&cat.flOutput, "o", "", outputUsage)
+}
+
+func (cat *Cat) Flags() *flag.FlagSet {
+	return &cat.flags
+}
+
+func (cat *Cat) Synopsis(w io.Writer) {
+	fmt.Fprintln(w,
+		"cat [-f] [--unread] [--since AGE] [--atom|--jsonfeed|"+
+			"--format TEMPLATE] [-o FILE] catpath... -- print the recent "+
+			"articles in feeds")
+}
+
+// Run prints the recent articles in each feed or category named, newest
+// first, as tail does. A catpath can also name a single article, as in
+// "/News/Feed/1234".
+// It carries on past failures, and exits with the code for the last one:
+// EX_NOINPUT if nothing is at a path, EX_DATAERR if it's ambiguous, and
+// EX_UNAVAILABLE if the server refused.
+// With -f, each article is followed by its content, rendered as plain text,
+// and a blank line.
+// With --atom or --jsonfeed, the articles are gathered up and printed as an
+// Atom feed or JSON Feed instead, content and all.
+// With --format, each article is printed as the template says, given
+// a HeadlineView.
+// With -o, it all goes to a file instead of stdout.
+func (cat *Cat) Run(args []string) {
+	cat.flags.Parse(args)
+
+	if cat.flHelp {
+		flagSetPrintUsage(cat.flags, os.Stdout, "cat")
+		exit(EX_SUCCESS)
+	}
+
+	gather := cat.flAtom || cat.flJSON
+	if cat.flags.NArg() < 1 || (cat.flAtom && cat.flJSON) ||
+		(gather && cat.flFormat != "") {
+		flagSetPrintUsage(cat.flags, os.Stderr, "cat")
+		exit(EX_USAGE)
+	}
+	if cat.flFormat != "" {
+		format, err := parseFormat(cat.flFormat, HeadlineView{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "cat: bad --format:", err)
+			exit(EX_USAGE)
+		}
+		cat.format = format
+	}
+	outputTo(cat.flOutput)
+
+	code := EX_SUCCESS
+	var gathered []ttrss.Headline
+	gatheredIDs := make(map[int]bool)
+	for _, catpath := range cat.flags.Args() {
+		item, article, err := ResolveArticlePath(catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "cat:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		var headlines []ttrss.Headline
+		if article != nil {
+			// Asking for an article by ID is asking for it, filters or no.
+			headlines = []ttrss.Headline{*article}
+		} else {
+			headlines, err = cat.getHeadlines(item)
+		}
+		if err == nil && (cat.flFull || gather) {
+			err = fillContent(headlines)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "cat: %s: %v\n", catpath, err)
+			code = EX_UNAVAILABLE
+			continue
+		}
+
+		if gather {
+			// Catpaths can overlap, as /News and /News/Tech do.
+			for _, h := range headlines {
+				if !gatheredIDs[h.ID] {
+					gatheredIDs[h.ID] = true
+					gathered = append(gathered, h)
+				}
+			}
+			continue
+		}
+
+		for _, h := range headlines {
+			if cat.format != nil {
+				err := printFormatted(os.Stdout, cat.format, viewHeadline(h))
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "cat:", err)
+					exit(EX_DATAERR)
+				}
+				continue
+			}
+			printHeadline(os.Stdout, h)
+			if cat.flFull {
+				text := htmlToText(h.Content)
+				fmt.Printf("\n%s\n\n", displayLines(text))
+			}
+		}
+	}
+
+	if !gather {
+		exit(code)
+	}
+
+	sort.SliceStable(gathered, func(i, j int) bool {
+		return gathered[i].Updated.After(gathered[j].Updated)
+	})
+	title := strings.Join(cat.flags.Args(), " ")
+	var err error
+	if cat.flJSON {
+		err = writeJSONFeed(os.Stdout, title, gathered)
+	} else {
+		escaped := make([]string, cat.flags.NArg())
+		for i, catpath := range cat.flags.Args() {
+			escaped[i] = url.PathEscape(catpath)
+		}
+		id := "cat:" + strings.Join(escaped, ",")
+		err = writeAtom(os.Stdout, title, id, gathered)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cat:", err)
+		code = EX_IOERR
+	}
+	exit(code)
+}
+
+// getHeadlines fetches the recent articles in item that pass the filters.
+// Without --since, that's the first page the server sends; with it, it's
+// every article updated since, however many pages that takes.
+func (cat *Cat) getHeadlines(item *ttrss.FeedTreeItem) (
+	headlines []ttrss.Headline, err error) {
+	req := headlinesRequestFor(item)
+	if cat.flUnread {
+		req.ViewMode = ttrss.VIEW_UNREAD
+	}
+	if cat.flSince == 0 {
+		return tt.GetHeadlines(req)
+	}
+
+	// The API has no way to ask by date, so page through them, newest
+	// first, until they're too old.
+	cutoff := time.Now().Add(-time.Duration(cat.flSince))
+	err = eachHeadlineWhile(req, func(h ttrss.Headline) bool {
+		if h.Updated.Before(cutoff) {
+			return false
+		}
+		headlines = append(headlines, h)
+		return true
+	})
+	return
+}
+
+// fillContent fills in the Content of each of headlines, fetching them all
+// in one go. Articles that have gone since being listed are left empty.
+func fillContent(headlines []ttrss.Headline) error {
+	ids := make([]int, len(headlines))
+	for i, h := range headlines {
+		ids[i] = h.ID
+	}
+	articles, err := tt.GetArticles(ids...)
+	if err != nil {
+		return err
+	}
+
+	contentByID := make(map[int]string, len(articles))
+	for _, article := range articles {
+		contentByID[article.ID] = article.Content
+	}
+	for i := range headlines {
+		headlines[i].Content = contentByID[headlines[i].ID]
+	}
+	return nil
+}
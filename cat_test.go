@@ -0,0 +1,42 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCatSincePages(t *testing.T) {
+	// Article i was updated i hours ago, so with --since 300h, the first
+	// 300 are printed, from two pages, and the third page is never asked
+	// for.
+	const articles = 3 * headlinePageSize
+	now := time.Now()
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "A"))),
+		"getHeadlines": func(req map[string]interface{}) interface{} {
+			skip, _ := req["skip"].(float64)
+			limit, _ := req["limit"].(float64)
+			var page []interface{}
+			for i := int(skip); i < articles && i < int(skip+limit); i++ {
+				page = append(page, map[string]interface{}{
+					"id": articles - i, "feed_id": 10, "title": "T",
+					"link": "http://example.com/", "score": 0,
+					"updated": now.Add(-time.Duration(i)*time.Hour -
+						time.Minute).Unix()})
+			}
+			return page
+		},
+	})
+
+	stdout, stderr, code := runTool(t, stub, "", "cat", "--since", "300h",
+		"/News/A")
+	lines := strings.Count(stdout, "\n")
+	pages := len(stub.called("getHeadlines"))
+	if code != EX_SUCCESS || lines != 300 || pages != 2 {
+		t.Errorf("cat --since 300h: got exit %d, %d articles from %d "+
+			"pages, stderr %q; want 300 from 2", code, lines, pages, stderr)
+	}
+}
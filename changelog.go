@@ -0,0 +1,52 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// ChangelogEntry records one attempt to change something on the server.
+// With --changelog, each is appended to the file as a line of JSON.
+type ChangelogEntry struct {
+	Time time.Time `json:"time"`
+
+	// Op is the subcommand that made the change, like "ln".
+	Op string `json:"op"`
+
+	// Path is the catpath of the category or feed changed, and ID its ID.
+	Path string `json:"path"`
+	ID   int    `json:"id"`
+
+	// To is where the item went, for a change that moves or renames it.
+	To string `json:"to,omitempty"`
+
+	// URL is the feed URL involved, if any.
+	URL string `json:"url,omitempty"`
+
+	// Result is "ok" if the change was made, and otherwise says why not.
+	Result string `json:"result"`
+}
+
+// logChange appends entry to the --changelog file, if there is one.
+// Failing to log is reported but not fatal: by now, the change is made.
+func logChange(entry ChangelogEntry) {
+	if flChangelog == "" {
+		return
+	}
+
+	entry.Time = time.Now()
+	file, err := os.OpenFile(flChangelog,
+		os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err == nil {
+		err = json.NewEncoder(file).Encode(entry)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		infof("warning: unable to update changelog: %v", err)
+	}
+}
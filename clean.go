@@ -0,0 +1,193 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"ttrss"
+)
+
+type Clean struct {
+	flHelp    bool
+	flRecurse bool
+	flDryRun  bool
+	flKeep    ageValue
+	flags     flag.FlagSet
+
+	// days is how many days of articles --keep keeps.
+	days int
+}
+
+func (clean *Clean) Init() {
+	clean.flags.Init("clean", flag.PanicOnError)
+
+	clean.flags.BoolVar(&clean.flHelp, "h", false, "help")
+	clean.flags.BoolVar(&clean.flHelp, "help", false, "help")
+
+	recurseHelp := "clean every feed in categories"
+	clean.flags.BoolVar(&clean.flRecurse, "r", false, recurseHelp)
+	clean.flags.BoolVar(&clean.flRecurse, "R", false, recurseHelp)
+
+	clean.flags.BoolVar(&clean.flDryRun, "dry-run", false,
+		"count what would be purged, but purge nothing")
+	clean.flags.Var(&clean.flKeep, "keep",
+		"purge articles older than `AGE`, in whole days, like 30d")
+}
+
+func (clean *Clean) Flags() *flag.FlagSet {
+	return &clean.flags
+}
+
+func (clean *Clean) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "clean [-R] [--dry-run] --keep AGE catpath... "+
+		"-- purge old articles")
+}
+
+// Run purges the articles older than --keep from each feed named, printing
+// how many went. The server only purges by the day, so --keep must be
+// a whole number of them. Starred articles are always kept, as are unread
+// ones unless the user has told the server to purge those too.
+// With --dry-run, it prints how many old articles there are instead, and
+// how many of those are unread.
+// It carries on past failures, and exits with the code for the last one:
+// EX_NOINPUT if nothing is at the path, EX_DATAERR if what is there is
+// a category and -R wasn't given, or is a virtual feed, and EX_UNAVAILABLE
+// if the server refused. Purging needs a server plugin.
+func (clean *Clean) Run(args []string) {
+	clean.flags.Parse(args)
+
+	if clean.flHelp {
+		flagSetPrintUsage(clean.flags, os.Stdout, "clean")
+		exit(EX_SUCCESS)
+	}
+
+	if clean.flags.NArg() < 1 {
+		flagSetPrintUsage(clean.flags, os.Stderr, "clean")
+		exit(EX_USAGE)
+	}
+
+	const day = 24 * time.Hour
+	keep := time.Duration(clean.flKeep)
+	if keep == 0 {
+		fmt.Fprintln(os.Stderr, "clean: --keep is required")
+		exit(EX_USAGE)
+	}
+	if keep < day || keep%day != 0 {
+		fmt.Fprintf(os.Stderr,
+			"clean: --keep must be a whole number of days, like 30d, "+
+				"not %s\n", &clean.flKeep)
+		exit(EX_USAGE)
+	}
+	clean.days = int(keep / day)
+
+	code := EX_SUCCESS
+	for _, catpath := range clean.flags.Args() {
+		item, err := ResolveCatPath(catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "clean:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		if itemCode := clean.clean(catpath, item); itemCode != EX_SUCCESS {
+			code = itemCode
+		}
+	}
+	exit(code)
+}
+
+// clean purges item, found at catpath, and returns the exit code for how
+// that went.
+func (clean *Clean) clean(catpath string, item *ttrss.FeedTreeItem) int {
+	if item.IsVirtual() {
+		fmt.Fprintf(os.Stderr,
+			"clean: %q is virtual: clean the feeds its articles are in\n",
+			catpath)
+		return EX_DATAERR
+	}
+	if item.Type == ttrss.Feed {
+		return clean.purge(catpath, item)
+	}
+
+	if !clean.flRecurse {
+		fmt.Fprintf(os.Stderr,
+			"clean: not a feed: %q is a category (use -R)\n", catpath)
+		return EX_DATAERR
+	}
+
+	code := EX_SUCCESS
+	walkCatPath(item, catpath, func(item *ttrss.FeedTreeItem,
+		catpath string) bool {
+		if item.IsVirtual() {
+			return false
+		}
+		if item.Type == ttrss.Feed {
+			if feedCode := clean.purge(catpath, item); feedCode != EX_SUCCESS {
+				code = feedCode
+			}
+		}
+		return true
+	})
+	return code
+}
+
+// purge purges the feed item, found at catpath, or with --dry-run, counts
+// what it would purge, and returns the exit code for how that went.
+func (clean *Clean) purge(catpath string, item *ttrss.FeedTreeItem) int {
+	if clean.flDryRun {
+		old, unread, err := clean.countOld(item)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "clean: %s: %v\n", catpath, err)
+			return EX_UNAVAILABLE
+		}
+		fmt.Printf("would purge %d %s from %s (%d unread)\n", old,
+			plural(old, "article", "articles"), display(catpath), unread)
+		return EX_SUCCESS
+	}
+
+	purged, err := tt.PurgeFeed(item.ID, clean.days)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected += purged
+	}
+	logChange(ChangelogEntry{Op: "clean", Path: catpath, ID: item.ID,
+		To: fmt.Sprintf("%dd", clean.days), Result: result})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "clean: %s: %v\n", catpath, err)
+		return EX_UNAVAILABLE
+	}
+	fmt.Printf("purged %d %s from %s\n", purged,
+		plural(purged, "article", "articles"), display(catpath))
+	return EX_SUCCESS
+}
+
+// countOld counts the articles in the feed item that are older than --keep
+// and not starred, and how many of those are unread. Whether those would be
+// purged is up to the server.
+func (clean *Clean) countOld(item *ttrss.FeedTreeItem) (old, unread int,
+	err error) {
+	cutoff := time.Now().AddDate(0, 0, -clean.days)
+	err = eachHeadline(headlinesRequestFor(item), func(h ttrss.Headline) {
+		if h.Marked || !h.Updated.Before(cutoff) {
+			return
+		}
+		old++
+		if h.Unread {
+			unread++
+		}
+	})
+	return
+}
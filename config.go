@@ -0,0 +1,161 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"ttrss"
+)
+
+type Config struct {
+	flHelp      bool
+	flPorcelain porcelainValue
+	flags       flag.FlagSet
+}
+
+func (config *Config) Init() {
+	config.flags.Init("config", flag.PanicOnError)
+
+	config.flags.BoolVar(&config.flHelp, "h", false, "help")
+	config.flags.BoolVar(&config.flHelp, "help", false, "help")
+
+	config.flags.Var(&config.flPorcelain, "porcelain", porcelainUsage)
+}
+
+func (config *Config) Flags() *flag.FlagSet {
+	return &config.flags
+}
+
+func (config *Config) Synopsis(w io.Writer) {
+	fmt.Fprintln(w,
+		"config [--porcelain] check -- check the configuration and try "+
+			"logging in")
+}
+
+// LoginFree lets config check report on a configuration that would keep
+// main() from ever getting as far as running a command.
+func (config *Config) LoginFree() {}
+
+func (config *Config) Run(args []string) {
+	config.flags.Parse(args)
+
+	if config.flHelp {
+		flagSetPrintUsage(config.flags, os.Stdout, "config")
+		exit(EX_SUCCESS)
+	}
+
+	if config.flags.NArg() != 1 || config.flags.Arg(0) != "check" {
+		flagSetPrintUsage(config.flags, os.Stderr, "config")
+		exit(EX_USAGE)
+	}
+
+	exit(checkConfig(os.Stdout, config.flPorcelain != ""))
+}
+
+// configCheck records the outcome of one of checkConfig's checks.
+type configCheck struct {
+	w         io.Writer
+	porcelain bool
+	exitCode  int
+}
+
+// pass reports that the check called name succeeded.
+func (c *configCheck) pass(name, detail string) {
+	if c.porcelain {
+		printPorcelain(c.w, "ok", name, detail)
+		return
+	}
+	fmt.Fprintf(c.w, "ok    %-8s %s\n", name, detail)
+}
+
+// fail reports that the check called name failed. The first failure decides
+// the exit code.
+func (c *configCheck) fail(name string, exitCode int, err error) {
+	if c.porcelain {
+		printPorcelain(c.w, "fail", name, err.Error())
+	} else {
+		fmt.Fprintf(c.w, "FAIL  %-8s %v\n", name, err)
+	}
+	if c.exitCode == EX_SUCCESS {
+		c.exitCode = exitCode
+	}
+}
+
+// checkConfig loads the configuration main() would, reports on each part of
+// it to w, and returns the exit code for the first failure, if any.
+// With porcelain, each report is a line of "ok" or "fail", the name of the
+// check, and the detail or error, separated by tabs.
+// Nothing is changed on either end, though it does log in.
+//
+// Each kind of failure gets its own exit code:
+//
+//   - dotfile unreadable or malformed: EX_DATAERR
+//   - dotfile readable by others: EX_NOPERM
+//   - address or --min-tls unusable: EX_CONFIG
+//   - server unreachable or not speaking the API: EX_UNAVAILABLE
+//   - login refused: EX_NOUSER
+func checkConfig(w io.Writer, porcelain bool) int {
+	c := &configCheck{w: w, porcelain: porcelain}
+
+	if err := applyDotfile(flDotfilePath); err != nil {
+		c.fail("dotfile", EX_DATAERR, err)
+	} else if info, err := os.Stat(flDotfilePath); os.IsNotExist(err) {
+		c.pass("dotfile", "none at "+flDotfilePath)
+	} else if err != nil {
+		c.fail("dotfile", EX_DATAERR, err)
+	} else if info.Mode().Perm()&0077 != 0 {
+		c.fail("dotfile", EX_NOPERM, fmt.Errorf(
+			"%s is readable by others (mode %v): chmod 600 it",
+			flDotfilePath, info.Mode().Perm()))
+	} else {
+		c.pass("dotfile", flDotfilePath)
+	}
+
+	addr, err := url.Parse(flAddr)
+	if err == nil && (addr.Scheme != "http" && addr.Scheme != "https") {
+		err = fmt.Errorf("address %q must start with http:// or https://",
+			flAddr)
+	} else if err == nil && addr.Host == "" {
+		err = fmt.Errorf("address %q has no host", flAddr)
+	}
+	if err != nil {
+		c.fail("addr", EX_CONFIG, err)
+		return c.exitCode
+	}
+	c.pass("addr", flAddr)
+
+	if err := configureClient(); err != nil {
+		c.fail("tls", EX_CONFIG, err)
+		return c.exitCode
+	}
+
+	// Ask something that needs no login, to tell "wrong place" apart from
+	// "wrong password".
+	tt.ApiEP = ttrss.APIEndpoint(flAddr)
+	_, err = tt.Call("isLoggedIn", map[string]interface{}{})
+	if err != nil {
+		c.fail("server", EX_UNAVAILABLE, err)
+		return c.exitCode
+	}
+	c.pass("server", tt.ApiEP)
+
+	if flPass == "" {
+		flPass, err = readPassword(os.Stdin, os.Stdout, "password")
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+	}
+	_, err = tt.Login(ttrss.ConnInfo{
+		HostURL: flAddr, User: flUser, Password: flPass})
+	if err != nil {
+		c.fail("login", EX_NOUSER, err)
+		return c.exitCode
+	}
+	c.pass("login", "as "+flUser)
+	return c.exitCode
+}
@@ -0,0 +1,84 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckConfig(t *testing.T) {
+	good := newStubServer(t, nil)
+	refusing := newStubServer(t, map[string]stubOp{
+		"login": func(map[string]interface{}) interface{} {
+			return stubError("LOGIN_ERROR")
+		},
+	})
+	gone := newStubServer(t, nil)
+	gone.Close()
+
+	tests := []struct {
+		name    string
+		dotfile string // contents, if any
+		mode    os.FileMode
+		addr    string
+		want    int
+		failed  string // the check that fails
+	}{
+		{"no dotfile", "", 0, good.URL, EX_SUCCESS, ""},
+		{"dotfile", `{"addr":"` + good.URL + `"}`, 0600, "",
+			EX_SUCCESS, ""},
+		{"malformed dotfile", `{"addr":`, 0600, good.URL, EX_DATAERR,
+			"dotfile"},
+		{"dotfile readable by others", `{}`, 0644, good.URL,
+			EX_NOPERM, "dotfile"},
+		{"not http", "", 0, "ftp://example.com/", EX_CONFIG, "addr"},
+		{"no host", "", 0, "http://", EX_CONFIG, "addr"},
+		{"server gone", "", 0, gone.URL, EX_UNAVAILABLE, "server"},
+		{"bad credentials", "", 0, refusing.URL, EX_NOUSER, "login"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useStub(t, good)
+			savedAddr, savedUser, savedPass, savedDotfile :=
+				flAddr, flUser, flPass, flDotfilePath
+			defer func() {
+				flAddr, flUser, flPass, flDotfilePath =
+					savedAddr, savedUser, savedPass, savedDotfile
+			}()
+			flAddr, flUser, flPass = test.addr, userDefault, "pass"
+			flDotfilePath = filepath.Join(t.TempDir(), "config")
+			if test.dotfile != "" {
+				err := ioutil.WriteFile(flDotfilePath,
+					[]byte(test.dotfile), test.mode)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Never mind the umask.
+				os.Chmod(flDotfilePath, test.mode)
+			}
+
+			var out bytes.Buffer
+			code := checkConfig(&out, true)
+			if code != test.want {
+				t.Errorf("checkConfig = %d, want %d; reported:\n%s",
+					code, test.want, &out)
+			}
+			failed := ""
+			for _, line := range strings.Split(out.String(), "\n") {
+				if strings.HasPrefix(line, "fail\t") {
+					failed = strings.Split(line, "\t")[1]
+					break
+				}
+			}
+			if failed != test.failed {
+				t.Errorf("checkConfig failed %q, want %q; reported:\n%s",
+					failed, test.failed, &out)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Describe prints the commands and their flags as JSON, for the benefit of
+// wrappers, completion scripts, and docs generators.
+type Describe struct {
+	flags flag.FlagSet
+}
+
+func (describe *Describe) Init() {
+	describe.flags.Init("__describe", flag.PanicOnError)
+}
+
+func (describe *Describe) Flags() *flag.FlagSet {
+	return &describe.flags
+}
+
+func (describe *Describe) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "__describe -- print commands and flags as JSON")
+}
+
+func (describe *Describe) LoginFree() {}
+
+// FlagSchema describes a flag, along with its one-letter alias, if any.
+type FlagSchema struct {
+	Name    string `json:"name"`
+	Short   string `json:"short,omitempty"`
+	Default string `json:"default"`
+	Usage   string `json:"usage"`
+}
+
+// CmdSchema describes a subcommand.
+type CmdSchema struct {
+	Name     string       `json:"name"`
+	Synopsis string       `json:"synopsis"`
+	Flags    []FlagSchema `json:"flags"`
+}
+
+// Schema describes the whole command line.
+type Schema struct {
+	Flags    []FlagSchema `json:"flags"`
+	Commands []CmdSchema  `json:"commands"`
+}
+
+func (describe *Describe) Run(args []string) {
+	describe.flags.Parse(args)
+
+	schema := Schema{Flags: describeFlags(flag.CommandLine)}
+	for name, cmd := range cmds {
+		var synopsis bytes.Buffer
+		cmd.Synopsis(&synopsis)
+		schema.Commands = append(schema.Commands, CmdSchema{
+			Name:     name,
+			Synopsis: strings.TrimSpace(synopsis.String()),
+			Flags:    describeFlags(cmd.Flags()),
+		})
+	}
+	sort.Slice(schema.Commands, func(i, j int) bool {
+		return schema.Commands[i].Name < schema.Commands[j].Name
+	})
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(schema); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// describeFlags lists the flags in fs, sorted by name.
+// Flags registered twice, once under a single letter, are listed once, with
+// the letter as the short form.
+func describeFlags(fs *flag.FlagSet) (schemas []FlagSchema) {
+	// Aliases share a Value, which points at the variable they both set.
+	byValue := make(map[flag.Value][]*flag.Flag)
+	var order []flag.Value
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := byValue[f.Value]; !ok {
+			order = append(order, f.Value)
+		}
+		byValue[f.Value] = append(byValue[f.Value], f)
+	})
+
+	schemas = []FlagSchema{}
+	for _, value := range order {
+		schema := FlagSchema{}
+		for _, f := range byValue[value] {
+			schema.Default = f.DefValue
+			schema.Usage = f.Usage
+			if len(f.Name) == 1 && schema.Short == "" {
+				schema.Short = f.Name
+			} else if schema.Name == "" {
+				schema.Name = f.Name
+			} else {
+				// A third spelling: describe it separately.
+				schemas = append(schemas, FlagSchema{
+					Name: f.Name, Default: f.DefValue, Usage: f.Usage})
+			}
+		}
+		if schema.Name == "" {
+			schema.Name, schema.Short = schema.Short, ""
+		}
+		schemas = append(schemas, schema)
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Name < schemas[j].Name
+	})
+	return
+}
@@ -0,0 +1,63 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestDescribeLs(t *testing.T) {
+	stub := newStubServer(t, nil)
+	stdout, stderr, code := runTool(t, stub, "", "__describe")
+	if code != EX_SUCCESS {
+		t.Fatalf("__describe: exit %d, stderr %q", code, stderr)
+	}
+	var schema Schema
+	if err := json.Unmarshal([]byte(stdout), &schema); err != nil {
+		t.Fatalf("__describe: %v in %s", err, stdout)
+	}
+
+	want := FlagSchema{Name: "Recurse", Short: "R", Default: "false",
+		Usage: "recurse into categories"}
+	for _, cmd := range schema.Commands {
+		if cmd.Name != "ls" {
+			continue
+		}
+		for _, f := range cmd.Flags {
+			if f.Short == "R" {
+				if f != want {
+					t.Errorf("__describe: ls -R is %+v, want %+v", f, want)
+				}
+				return
+			}
+		}
+		t.Fatalf("__describe: ls has no -R among %+v", cmd.Flags)
+	}
+	t.Fatal("__describe: no ls command")
+}
+
+func TestDescribeFlags(t *testing.T) {
+	var fs flag.FlagSet
+	var help, all bool
+	var count int
+	fs.BoolVar(&help, "h", false, "help")
+	fs.BoolVar(&help, "help", false, "help")
+	fs.IntVar(&count, "n", 10, "how many")
+	fs.BoolVar(&all, "all", false, "everything")
+	fs.BoolVar(&all, "a", false, "everything")
+	fs.BoolVar(&all, "every", false, "everything")
+
+	got := describeFlags(&fs)
+	want := []FlagSchema{
+		{"all", "a", "false", "everything"},
+		{"every", "", "false", "everything"},
+		{"help", "h", "false", "help"},
+		{"n", "", "10", "how many"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("describeFlags = %+v, want %+v", got, want)
+	}
+}
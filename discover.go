@@ -0,0 +1,212 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"ttrss"
+)
+
+// feedTypes are the types a web page's <link rel="alternate"> tags give for
+// the feeds it offers.
+var feedTypes = map[string]bool{
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+	"application/rdf+xml":   true,
+	"application/rss+xml":   true,
+}
+
+// discoverTimeout bounds fetching a feed, or a page offering feeds.
+const discoverTimeout = 30 * time.Second
+
+// DiscoveredFeed is a feed offered by a web page.
+type DiscoveredFeed struct {
+	URL   string
+	Title string
+}
+
+// discoverFeeds fetches pageURL, as feedUser if that's set, and reports
+// whether it's a web page rather than a feed. If it is, feeds are the feeds
+// it offers, in the order it offers them.
+func discoverFeeds(pageURL, feedUser, feedPass string) (
+	feeds []DiscoveredFeed, isPage bool, err error) {
+	body, mediaType, base, err := fetchFeed(pageURL, feedUser, feedPass)
+	if err != nil || !isPageType(mediaType) {
+		return
+	}
+	return feedLinks(string(body), base), true, nil
+}
+
+// fetchFeed fetches feedURL, as feedUser if that's set, and returns what's
+// there, along with its media type and the URL it was found at in the end,
+// after any redirects. Responses are bounded as the server's are, by
+// --max-response-bytes.
+func fetchFeed(feedURL, feedUser, feedPass string) (body []byte,
+	mediaType string, found *url.URL, err error) {
+	req, err := http.NewRequest("GET", feedURL, nil)
+	if err != nil {
+		return
+	}
+	if feedUser != "" {
+		req.SetBasicAuth(feedUser, feedPass)
+	}
+
+	// Honor --min-tls here too.
+	client := http.Client{
+		Transport: tt.Client.Transport, Timeout: discoverTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("fetching %s: %s", feedURL, resp.Status)
+		return
+	}
+
+	maxBytes := tt.MaxResponseBytes
+	if maxBytes <= 0 {
+		maxBytes = ttrss.DefaultMaxResponseBytes
+	}
+	body, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return
+	}
+	if int64(len(body)) > maxBytes {
+		err = fmt.Errorf("fetching %s: more than %d bytes", feedURL,
+			maxBytes)
+		return
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, _ = mime.ParseMediaType(contentType)
+	return body, mediaType, resp.Request.URL, nil
+}
+
+// isPageType reports whether mediaType is that of a web page.
+func isPageType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// feedLinks finds the feeds offered by the <link> tags in page, found at
+// base. Each feed is listed once.
+// Like htmlToText, this is no HTML parser, but pages are regular enough
+// about their <link> tags for it not to matter.
+func feedLinks(page string, base *url.URL) (feeds []DiscoveredFeed) {
+	seen := make(map[string]bool)
+	rest := page
+	for {
+		lt := strings.IndexByte(rest, '<')
+		if lt < 0 {
+			return
+		}
+		rest = rest[lt:]
+		if strings.HasPrefix(rest, "<!--") {
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				return
+			}
+			rest = rest[end+len("-->"):]
+			continue
+		}
+
+		gt := strings.IndexByte(rest, '>')
+		if gt < 0 {
+			return
+		}
+		tag := rest[1:gt]
+		rest = rest[gt+1:]
+
+		name, attrs := parseTag(tag)
+		if name != "link" || !hasToken(attrs["rel"], "alternate") {
+			continue
+		}
+		mediaType, _, _ := mime.ParseMediaType(attrs["type"])
+		if !feedTypes[mediaType] || attrs["href"] == "" {
+			continue
+		}
+		ref, err := url.Parse(attrs["href"])
+		if err != nil {
+			continue
+		}
+		feedURL := base.ResolveReference(ref).String()
+		if seen[feedURL] {
+			continue
+		}
+		seen[feedURL] = true
+		feeds = append(feeds, DiscoveredFeed{
+			URL: feedURL, Title: strings.TrimSpace(attrs["title"])})
+	}
+}
+
+// parseTag splits the inside of an HTML tag into its name and attributes,
+// both with lowercase names. Attribute values are unquoted and unescaped.
+func parseTag(tag string) (name string, attrs map[string]string) {
+	tag = strings.TrimSuffix(tag, "/")
+	end := strings.IndexAny(tag, " \t\r\n")
+	if end < 0 {
+		end = len(tag)
+	}
+	name = strings.ToLower(tag[:end])
+	rest := tag[end:]
+
+	attrs = make(map[string]string)
+	for {
+		rest = strings.TrimLeft(rest, " \t\r\n")
+		if rest == "" {
+			return
+		}
+		end := strings.IndexAny(rest, "= \t\r\n")
+		if end < 0 {
+			end = len(rest)
+		}
+		attr := strings.ToLower(rest[:end])
+		rest = strings.TrimLeft(rest[end:], " \t\r\n")
+		if !strings.HasPrefix(rest, "=") {
+			attrs[attr] = ""
+			continue
+		}
+		rest = strings.TrimLeft(rest[1:], " \t\r\n")
+
+		var value string
+		if rest != "" && (rest[0] == '"' || rest[0] == '\'') {
+			quote := rest[0]
+			rest = rest[1:]
+			end := strings.IndexByte(rest, quote)
+			if end < 0 {
+				value, rest = rest, ""
+			} else {
+				value, rest = rest[:end], rest[end+1:]
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t\r\n")
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		attrs[attr] = html.UnescapeString(value)
+	}
+}
+
+// hasToken reports whether the space-separated list has token in it, as rel
+// attributes do, ignoring case.
+func hasToken(list, token string) bool {
+	for _, field := range strings.Fields(list) {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
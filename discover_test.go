@@ -0,0 +1,128 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFetchFeedMaxResponseBytes(t *testing.T) {
+	stub := newStubServer(t, nil)
+	stub.feeds["/feed.xml"] = stubRSS
+	useStub(t, stub)
+
+	tests := []struct {
+		maxBytes int64
+		tooLarge bool
+	}{
+		{0, false},
+		{-1, false},
+		{int64(len(stubRSS)), false},
+		{int64(len(stubRSS)) - 1, true},
+	}
+	for _, test := range tests {
+		tt.MaxResponseBytes = test.maxBytes
+		body, _, _, err := fetchFeed(stub.URL+"/feed.xml", "", "")
+		tooLarge := err != nil && strings.Contains(err.Error(), "more than")
+		if tooLarge != test.tooLarge || err == nil && string(body) != stubRSS {
+			t.Errorf("fetchFeed with MaxResponseBytes %d: got %q, %v",
+				test.maxBytes, body, err)
+		}
+	}
+}
+
+func TestFeedLinks(t *testing.T) {
+	base, err := url.Parse("https://example.com/blog/post.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, page string
+		want       []DiscoveredFeed
+	}{
+		{"none", "<html><head><title>Hi</title></head></html>", nil},
+		{"absolute",
+			`<link rel="alternate" type="application/rss+xml" ` +
+				`href="http://feeds.example.net/rss" title=" News ">`,
+			[]DiscoveredFeed{{"http://feeds.example.net/rss", "News"}}},
+		{"relative to the page",
+			`<link rel=alternate type="application/atom+xml" ` +
+				`href="atom.xml">`,
+			[]DiscoveredFeed{
+				{"https://example.com/blog/atom.xml", ""}}},
+		{"relative to the root",
+			`<LINK REL="Alternate" TYPE="application/feed+json" ` +
+				`HREF="/feed.json"/>`,
+			[]DiscoveredFeed{{"https://example.com/feed.json", ""}}},
+		{"escaped href",
+			`<link rel="alternate" type="application/rss+xml" ` +
+				`href="/rss?a=1&amp;b=2">`,
+			[]DiscoveredFeed{{"https://example.com/rss?a=1&b=2", ""}}},
+		{"commented out",
+			`<!-- <link rel="alternate" type="application/rss+xml" ` +
+				`href="/old.rss"> --><link rel="alternate" ` +
+				`type="application/rdf+xml" href="/new.rdf">`,
+			[]DiscoveredFeed{{"https://example.com/new.rdf", ""}}},
+		{"unterminated comment",
+			`<!-- <link rel="alternate" type="application/rss+xml" ` +
+				`href="/old.rss">`, nil},
+		{"duplicates",
+			`<link rel="alternate" type="application/rss+xml" ` +
+				`href="/rss" title="First">` +
+				`<link rel="alternate" type="application/rss+xml" ` +
+				`href="https://example.com/rss" title="Second">` +
+				`<link rel="alternate" type="application/atom+xml" ` +
+				`href="/atom">`,
+			[]DiscoveredFeed{
+				{"https://example.com/rss", "First"},
+				{"https://example.com/atom", ""}}},
+		{"not feeds",
+			`<link rel="stylesheet" type="text/css" href="/s.css">` +
+				`<link rel="alternate" type="text/html" href="/fr/">` +
+				`<link rel="alternate" type="application/rss+xml">` +
+				`<a rel="alternate" type="application/rss+xml" ` +
+				`href="/a.rss">`, nil},
+		{"media type parameters",
+			`<link rel="alternate home" ` +
+				`type="application/rss+xml; charset=utf-8" href="/rss">`,
+			[]DiscoveredFeed{{"https://example.com/rss", ""}}},
+	}
+	for _, test := range tests {
+		got := feedLinks(test.page, base)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag, name string
+		attrs     map[string]string
+	}{
+		{"br", "br", map[string]string{}},
+		{"br/", "br", map[string]string{}},
+		{`LINK REL="Alternate"`, "link",
+			map[string]string{"rel": "Alternate"}},
+		{`link href='/a "b"' title="it's"`, "link",
+			map[string]string{"href": `/a "b"`, "title": "it's"}},
+		{"link href=/rss type = text/html /", "link",
+			map[string]string{"href": "/rss", "type": "text/html"}},
+		{"input\tdisabled\nvalue=x", "input",
+			map[string]string{"disabled": "", "value": "x"}},
+		{`a title="Fish &amp; chips"`, "a",
+			map[string]string{"title": "Fish & chips"}},
+		{`a title="unterminated`, "a",
+			map[string]string{"title": "unterminated"}},
+	}
+	for _, test := range tests {
+		name, attrs := parseTag(test.tag)
+		if name != test.name || !reflect.DeepEqual(attrs, test.attrs) {
+			t.Errorf("parseTag(%q) = %q, %q; want %q, %q", test.tag,
+				name, attrs, test.name, test.attrs)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"ttrss"
+)
+
+type Du struct {
+	flHelp      bool
+	flAll       bool
+	flSummary   bool
+	flTotal     bool
+	flPorcelain porcelainValue
+	flags       flag.FlagSet
+}
+
+func (du *Du) Init() {
+	du.flags.Init("du", flag.PanicOnError)
+
+	du.flags.BoolVar(&du.flHelp, "h", false, "help")
+	du.flags.BoolVar(&du.flHelp, "help", false, "help")
+
+	du.flags.BoolVar(&du.flAll, "a", false,
+		"list feeds as well as categories")
+	du.flags.BoolVar(&du.flSummary, "s", false,
+		"list only a total for each catpath")
+	du.flags.BoolVar(&du.flTotal, "c", false,
+		"finish with a grand total")
+	du.flags.Var(&du.flPorcelain, "porcelain", porcelainUsage)
+}
+
+func (du *Du) Flags() *flag.FlagSet {
+	return &du.flags
+}
+
+func (du *Du) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "du [-acs] [--porcelain] [catpath...] "+
+		"-- count unread articles")
+}
+
+// Run prints how many unread articles there are in each category at or
+// below each catpath (by default, /), deepest first, as du(1) does.
+// As with ls, the server's own items are left out unless the count starts
+// among them.
+// With --porcelain, the lines are the same, but escaped for scripts, and the
+// grand total has an empty catpath rather than "total".
+func (du *Du) Run(args []string) {
+	du.flags.Parse(args)
+
+	if du.flHelp {
+		flagSetPrintUsage(du.flags, os.Stdout, "du")
+		exit(EX_SUCCESS)
+	}
+
+	catpaths := du.flags.Args()
+	if len(catpaths) == 0 {
+		catpaths = []string{"/"}
+	}
+
+	counters, err := tt.GetCounters()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "du:", err)
+		exit(EX_UNAVAILABLE)
+	}
+
+	code := EX_SUCCESS
+	total := 0
+	for _, catpath := range catpaths {
+		item, err := ResolveCatPath(catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "du:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		total += du.count(item, catpath, item.IsVirtual(), counters, true)
+	}
+
+	if du.flTotal && du.flPorcelain != "" {
+		printPorcelain(os.Stdout, fmt.Sprint(total), "")
+	} else if du.flTotal {
+		fmt.Printf("%d\ttotal\n", total)
+	}
+	exit(code)
+}
+
+// count returns the unread count of item, found at catpath: the sum of its
+// feeds' counts, if it's a category. Counts are printed along the way.
+func (du *Du) count(item *ttrss.FeedTreeItem, catpath string,
+	showVirtual bool, counters ttrss.Counters, top bool) (unread int) {
+	if item.Type == ttrss.Feed {
+		unread = counters.Feeds[item.ID]
+	}
+	for i := range item.Items {
+		child := &item.Items[i]
+		if child.IsVirtual() && !showVirtual {
+			continue
+		}
+		childPath := strings.TrimSuffix(catpath, "/") + "/" +
+			ttrss.EscapePathComponent(child.Name)
+		unread += du.count(child, childPath, showVirtual, counters, false)
+	}
+
+	show := top || !du.flSummary &&
+		(item.Type == ttrss.Category || du.flAll)
+	if show && du.flPorcelain != "" {
+		printPorcelain(os.Stdout, fmt.Sprint(unread), catpath)
+	} else if show {
+		fmt.Printf("%d\t%s\n", unread, display(catpath))
+	}
+	return
+}
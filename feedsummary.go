@@ -0,0 +1,168 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+// FeedSummary sums up a feed as fetched, before subscribing to it.
+type FeedSummary struct {
+	// Format is "RSS", "RSS 1.0", "Atom", or "JSON Feed".
+	Format string
+	Title  string
+	Items  int
+
+	// Latest is when the newest item was published or updated, or zero if
+	// no item says.
+	Latest time.Time
+}
+
+// Just enough of RSS 2.0, RSS 1.0 (RDF), and Atom to sum them up. Elements
+// are matched whatever their namespace, so Date is RSS 1.0's dc:date.
+type xmlFeed struct {
+	XMLName xml.Name
+	Title   string `xml:"title"`
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []xmlItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []xmlItem `xml:"item"`
+	Entries []xmlItem `xml:"entry"`
+}
+
+type xmlItem struct {
+	PubDate   string `xml:"pubDate"`
+	Date      string `xml:"date"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// Just enough of JSON Feed to sum it up.
+type jsonFeedSummary struct {
+	Version string `json:"version"`
+	Title   string `json:"title"`
+	Items   []struct {
+		Published string `json:"date_published"`
+		Modified  string `json:"date_modified"`
+	} `json:"items"`
+}
+
+// feedDateLayouts are the date formats found in feeds in the wild: RFC 3339
+// for Atom and JSON Feed, RFC 822 and its variations for RSS.
+var feedDateLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+}
+
+// summarizeFeed parses body, a feed of type mediaType, and sums it up.
+// It fails if body is not RSS, Atom, or JSON Feed.
+func summarizeFeed(body []byte, mediaType string) (
+	summary FeedSummary, err error) {
+	if strings.Contains(mediaType, "json") ||
+		bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")) {
+		return summarizeJSONFeed(body)
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charsetReader
+	var feed xmlFeed
+	if err = decoder.Decode(&feed); err != nil {
+		return summary, fmt.Errorf("not a feed: %v", err)
+	}
+
+	var items []xmlItem
+	switch feed.XMLName.Local {
+	case "rss":
+		summary.Format = "RSS"
+		summary.Title = feed.Channel.Title
+		items = feed.Channel.Items
+	case "RDF":
+		summary.Format = "RSS 1.0"
+		summary.Title = feed.Channel.Title
+		items = feed.Items
+	case "feed":
+		summary.Format = "Atom"
+		summary.Title = feed.Title
+		items = feed.Entries
+	default:
+		return summary, fmt.Errorf("not a feed: <%s> is not RSS or Atom",
+			feed.XMLName.Local)
+	}
+
+	summary.Title = strings.TrimSpace(summary.Title)
+	summary.Items = len(items)
+	for _, item := range items {
+		summary.noteDate(item.PubDate, item.Date, item.Published,
+			item.Updated)
+	}
+	return
+}
+
+// charsetReader decodes input, in charset, for the XML decoder. Only
+// ISO-8859-1 is decoded properly; only the structure matters here, so other
+// charsets pass through with anything the decoder would choke on replaced.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	body, err := ioutil.ReadAll(input)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "iso_8859-1", "latin1", "l1":
+		runes := make([]rune, len(body))
+		for i, b := range body {
+			runes[i] = rune(b)
+		}
+		return strings.NewReader(string(runes)), nil
+	}
+	return bytes.NewReader(bytes.ToValidUTF8(body, []byte("\uFFFD"))), nil
+}
+
+// summarizeJSONFeed is summarizeFeed for JSON Feed.
+func summarizeJSONFeed(body []byte) (summary FeedSummary, err error) {
+	var feed jsonFeedSummary
+	if err = json.Unmarshal(body, &feed); err != nil {
+		return summary, fmt.Errorf("not a feed: %v", err)
+	}
+	if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+		return summary, errors.New("not a feed: JSON, but not JSON Feed")
+	}
+
+	summary.Format = "JSON Feed"
+	summary.Title = strings.TrimSpace(feed.Title)
+	summary.Items = len(feed.Items)
+	for _, item := range feed.Items {
+		summary.noteDate(item.Published, item.Modified)
+	}
+	return
+}
+
+// noteDate moves Latest up to the latest of dates that can be parsed.
+func (summary *FeedSummary) noteDate(dates ...string) {
+	for _, date := range dates {
+		date = strings.TrimSpace(date)
+		for _, layout := range feedDateLayouts {
+			t, err := time.Parse(layout, date)
+			if err != nil {
+				continue
+			}
+			if t.After(summary.Latest) {
+				summary.Latest = t
+			}
+			break
+		}
+	}
+}
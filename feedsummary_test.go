@@ -0,0 +1,124 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeFeed(t *testing.T) {
+	tests := []struct {
+		name, mediaType, body string
+		want                  FeedSummary
+		err                   string
+	}{
+		{"RSS", "application/rss+xml", `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+  <title> Example </title>
+  <item><pubDate>Tue, 30 Apr 2013 12:00:00 GMT</pubDate></item>
+  <item><pubDate>Wed, 01 May 2013 12:00:00 +0000</pubDate></item>
+  <item><title>Undated</title></item>
+</channel></rss>`,
+			FeedSummary{"RSS", "Example", 3,
+				time.Date(2013, 5, 1, 12, 0, 0, 0, time.UTC)}, ""},
+		{"RDF", "application/rdf+xml", `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+    xmlns="http://purl.org/rss/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel><title>Old school</title></channel>
+  <item><dc:date>2013-05-01T12:00:00Z</dc:date></item>
+</rdf:RDF>`,
+			FeedSummary{"RSS 1.0", "Old school", 1,
+				time.Date(2013, 5, 1, 12, 0, 0, 0, time.UTC)}, ""},
+		{"Atom", "application/atom+xml", `<?xml version="1.0"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Atomic</title>
+  <entry><published>2013-04-01T00:00:00Z</published>
+    <updated>2013-05-01T12:00:00Z</updated></entry>
+</feed>`,
+			FeedSummary{"Atom", "Atomic", 1,
+				time.Date(2013, 5, 1, 12, 0, 0, 0, time.UTC)}, ""},
+		{"JSON Feed", "application/feed+json", `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "Jason",
+  "items": [
+    {"id": "1", "date_published": "2013-05-01T12:00:00Z"},
+    {"id": "2"}
+  ]
+}`,
+			FeedSummary{"JSON Feed", "Jason", 2,
+				time.Date(2013, 5, 1, 12, 0, 0, 0, time.UTC)}, ""},
+		{"JSON Feed sent as text", "text/plain",
+			` {"version": "https://jsonfeed.org/version/1", "items": []}`,
+			FeedSummary{Format: "JSON Feed"}, ""},
+		{"other charset", "text/xml",
+			`<?xml version="1.0" encoding="ISO-8859-1"?>` +
+				`<rss><channel><title>Caf` + "\xe9" +
+				`</title></channel></rss>`,
+			FeedSummary{Format: "RSS", Title: "Café"}, ""},
+		{"undecoded charset", "text/xml",
+			`<?xml version="1.0" encoding="windows-1252"?>` +
+				`<rss><channel><title>` + "\x93Hi\x94" +
+				`</title></channel></rss>`,
+			FeedSummary{Format: "RSS", Title: "\uFFFDHi\uFFFD"}, ""},
+		{"HTML", "text/html", "<html><body>Hi</body></html>",
+			FeedSummary{}, "<html> is not RSS or Atom"},
+		{"other JSON", "application/json", `{"version": "2"}`,
+			FeedSummary{}, "JSON, but not JSON Feed"},
+		{"garbage", "text/xml", "not even XML",
+			FeedSummary{}, "not a feed"},
+	}
+	for _, test := range tests {
+		got, err := summarizeFeed([]byte(test.body), test.mediaType)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s: got error %v, want %q", test.name, err,
+					test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if got.Format != test.want.Format ||
+			got.Title != test.want.Title ||
+			got.Items != test.want.Items ||
+			!got.Latest.Equal(test.want.Latest) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestNoteDate(t *testing.T) {
+	want := time.Date(2013, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []string{
+		"2013-05-01T12:00:00Z",
+		"2013-05-01T14:00:00+02:00",
+		"Wed, 01 May 2013 12:00:00 +0000",
+		"Wed, 01 May 2013 12:00:00 UTC",
+		"Wed, 1 May 2013 08:00:00 -0400",
+		"Wed, 1 May 2013 12:00:00 GMT",
+		"1 May 2013 12:00:00 +0000",
+		"1 May 2013 12:00:00 UTC",
+		"  2013-05-01T12:00:00Z\n",
+	}
+	for _, date := range tests {
+		var summary FeedSummary
+		summary.noteDate(date)
+		if !summary.Latest.Equal(want) {
+			t.Errorf("noteDate(%q): Latest = %v, want %v", date,
+				summary.Latest, want)
+		}
+	}
+
+	var summary FeedSummary
+	summary.noteDate("yesterday", "", "2013-04-01T00:00:00Z",
+		"Wed, 01 May 2013 12:00:00 +0000", "2013-04-15T00:00:00Z")
+	if !summary.Latest.Equal(want) {
+		t.Errorf("Latest = %v, want the latest date, %v",
+			summary.Latest, want)
+	}
+}
@@ -0,0 +1,255 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"ttrss"
+)
+
+type Find struct {
+	flHelp    bool
+	flName    string
+	flIName   string
+	flType    string
+	flURL     string
+	flSort    string
+	flReverse bool
+	flLimit   int
+	flags     flag.FlagSet
+}
+
+// findSortKeys are the keys find can sort by.
+var findSortKeys = map[string]bool{
+	"path": true, "name": true, "updated": true, "unread": true, "none": true,
+}
+
+// findMatch is an item that passed find's tests, and where it was found.
+type findMatch struct {
+	path string
+	item *ttrss.FeedTreeItem
+}
+
+func (find *Find) Init() {
+	find.flags.Init("find", flag.PanicOnError)
+
+	find.flags.BoolVar(&find.flHelp, "h", false, "help")
+	find.flags.BoolVar(&find.flHelp, "help", false, "help")
+
+	find.flags.StringVar(&find.flName, "name", "",
+		"only items whose name matches the wildcard `PATTERN`")
+	find.flags.StringVar(&find.flIName, "iname", "",
+		"like -name, but ignoring case")
+	find.flags.StringVar(&find.flType, "type", "",
+		"only feeds (`f`) or categories (d)")
+	find.flags.StringVar(&find.flURL, "url", "",
+		"only feeds whose URL matches the wildcard `PATTERN`")
+	find.flags.StringVar(&find.flSort, "sort", "path",
+		"sort by `KEY`: path, name, updated (most recent first), "+
+			"unread (most first), or none (the server's order)")
+	find.flags.BoolVar(&find.flReverse, "reverse", false,
+		"reverse the sort order")
+	find.flags.IntVar(&find.flLimit, "limit", 0,
+		"stop after `N` items, noting how many more there were "+
+			"(0 means no limit)")
+}
+
+func (find *Find) Flags() *flag.FlagSet {
+	return &find.flags
+}
+
+func (find *Find) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "find [catpath...] [-name P] [-iname P] [-type f|d] "+
+		"[-url P] [-sort KEY] [-reverse] [-limit N] "+
+		"-- search for feeds and categories")
+}
+
+// Run prints the catpath of everything at or below each catpath (by
+// default, /) that passes all the tests given, sorted by path, or as -sort
+// says. With -limit, it stops after that many, noting how many more there
+// were; with -sort none, those are only counted, not kept.
+// As with ls, the server's own items are left out unless the search starts
+// among them.
+func (find *Find) Run(args []string) {
+	// As with find(1), the catpaths come before the tests.
+	var catpaths []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		catpaths = append(catpaths, args[0])
+		args = args[1:]
+	}
+	find.flags.Parse(args)
+
+	if find.flHelp {
+		flagSetPrintUsage(find.flags, os.Stdout, "find")
+		exit(EX_SUCCESS)
+	}
+
+	catpaths = append(catpaths, find.flags.Args()...)
+	if len(catpaths) == 0 {
+		catpaths = []string{"/"}
+	}
+	if find.flType != "" && find.flType != "f" && find.flType != "d" ||
+		!findSortKeys[find.flSort] || find.flLimit < 0 {
+		flagSetPrintUsage(find.flags, os.Stderr, "find")
+		exit(EX_USAGE)
+	}
+
+	// The tree knows neither feed URLs nor update times; getFeeds does.
+	var feedByID map[int]ttrss.FeedInfo
+	if find.flURL != "" || find.flSort == "updated" {
+		feeds, err := tt.GetFeeds(
+			ttrss.CATEGORY_FEEDS_NOT_VIRTUAL, false, false)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "find:", err)
+			exit(EX_UNAVAILABLE)
+		}
+		feedByID = make(map[int]ttrss.FeedInfo, len(feeds))
+		for _, feed := range feeds {
+			feedByID[feed.ID] = feed
+		}
+	}
+	var counters ttrss.Counters
+	if find.flSort == "unread" {
+		var err error
+		if counters, err = tt.GetCounters(); err != nil {
+			fmt.Fprintln(os.Stderr, "find:", err)
+			exit(EX_UNAVAILABLE)
+		}
+	}
+
+	code := EX_SUCCESS
+	var matches []findMatch
+	more := 0
+	for _, catpath := range catpaths {
+		item, err := ResolveCatPath(catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "find:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		showVirtual := item.IsVirtual()
+		walkCatPath(item, catpath, func(item *ttrss.FeedTreeItem,
+			itemPath string) bool {
+			if item.IsVirtual() && !showVirtual {
+				return false
+			}
+			if !find.passes(item, feedByID) {
+				return true
+			}
+			// Unsorted, the first found are the first printed, so the rest
+			// needn't be kept.
+			if find.flSort == "none" && !find.flReverse &&
+				find.flLimit > 0 && len(matches) >= find.flLimit {
+				more++
+				return true
+			}
+			matches = append(matches, findMatch{itemPath, item})
+			return true
+		})
+	}
+
+	sortMatches(matches, find.flSort, find.flReverse, counters, feedByID)
+	if find.flLimit > 0 && len(matches) > find.flLimit {
+		more += len(matches) - find.flLimit
+		matches = matches[:find.flLimit]
+	}
+	for _, match := range matches {
+		fmt.Println(display(match.path))
+	}
+	if more > 0 {
+		infof("…(truncated, %d more)", more)
+	}
+	exit(code)
+}
+
+// sortMatches sorts matches by key, one of findSortKeys, in reverse if
+// reverse is set. Ties are broken by path, ignoring case and then not. For
+// "none", they're left in the order found, or reversed.
+// counters are needed to sort by unread count, and feedByID to sort by
+// last update.
+func sortMatches(matches []findMatch, key string, reverse bool,
+	counters ttrss.Counters, feedByID map[int]ttrss.FeedInfo) {
+	before := func(a, b findMatch) bool {
+		switch key {
+		case "name":
+			nameA, nameB := strings.ToLower(a.item.Name),
+				strings.ToLower(b.item.Name)
+			if nameA != nameB {
+				return nameA < nameB
+			}
+		case "updated":
+			timeA := updatedIn(a.item, feedByID)
+			timeB := updatedIn(b.item, feedByID)
+			if !timeA.Equal(timeB) {
+				return timeA.After(timeB)
+			}
+		case "unread":
+			unreadA := unreadIn(a.item, counters)
+			unreadB := unreadIn(b.item, counters)
+			if unreadA != unreadB {
+				return unreadA > unreadB
+			}
+		}
+		pathA, pathB := strings.ToLower(a.path), strings.ToLower(b.path)
+		if pathA != pathB {
+			return pathA < pathB
+		}
+		return a.path < b.path
+	}
+	if key == "none" {
+		if reverse {
+			for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+				matches[i], matches[j] = matches[j], matches[i]
+			}
+		}
+		return
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if reverse {
+			return before(matches[j], matches[i])
+		}
+		return before(matches[i], matches[j])
+	})
+}
+
+// passes reports whether item passes all the tests given.
+func (find *Find) passes(item *ttrss.FeedTreeItem,
+	feedByID map[int]ttrss.FeedInfo) bool {
+	if find.flName != "" && !globMatch(find.flName, item.Name) {
+		return false
+	}
+	if find.flIName != "" && !globMatch(strings.ToLower(find.flIName),
+		strings.ToLower(item.Name)) {
+		return false
+	}
+	switch find.flType {
+	case "f":
+		if item.Type != ttrss.Feed {
+			return false
+		}
+	case "d":
+		if item.Type != ttrss.Category {
+			return false
+		}
+	}
+	if find.flURL != "" {
+		feed, ok := feedByID[item.ID]
+		if item.Type != ttrss.Feed || !ok ||
+			!globMatch(find.flURL, feed.FeedURL) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+	"ttrss"
+)
+
+func TestSortMatches(t *testing.T) {
+	feed := func(id int, name string) *ttrss.FeedTreeItem {
+		return &ttrss.FeedTreeItem{ID: id, Name: name, Type: ttrss.Feed}
+	}
+	cat := func(id int, name string,
+		items ...*ttrss.FeedTreeItem) *ttrss.FeedTreeItem {
+		item := &ttrss.FeedTreeItem{ID: id, Name: name,
+			Type: ttrss.Category}
+		for _, child := range items {
+			item.Items = append(item.Items, *child)
+		}
+		return item
+	}
+	// Found in tree order; the category holds feeds 11 and 12.
+	found := []findMatch{
+		{"/News/zeta", feed(10, "zeta")},
+		{"/News/Blogs", cat(2, "Blogs", feed(11, "x"), feed(12, "y"))},
+		{"/Alpha", feed(13, "Alpha")},
+		{"/News/alpha", feed(14, "alpha")},
+	}
+	day := func(n int) time.Time { return time.Unix(int64(n)*86400, 0) }
+	feedByID := map[int]ttrss.FeedInfo{
+		10: {LastUpdated: day(3)},
+		11: {LastUpdated: day(1)},
+		12: {LastUpdated: day(5)},
+		13: {LastUpdated: day(2)},
+		14: {LastUpdated: day(2)},
+	}
+	counters := ttrss.Counters{Feeds: map[int]int{
+		10: 4, 11: 1, 12: 2, 13: 0, 14: 9}}
+
+	tests := []struct {
+		key     string
+		reverse bool
+		want    []string
+	}{
+		{"path", false, []string{
+			"/Alpha", "/News/alpha", "/News/Blogs", "/News/zeta"}},
+		{"path", true, []string{
+			"/News/zeta", "/News/Blogs", "/News/alpha", "/Alpha"}},
+		// Alpha and alpha tie on name, so go by path.
+		{"name", false, []string{
+			"/Alpha", "/News/alpha", "/News/Blogs", "/News/zeta"}},
+		{"name", true, []string{
+			"/News/zeta", "/News/Blogs", "/News/alpha", "/Alpha"}},
+		// Blogs last updated when its feed 12 did.
+		{"updated", false, []string{
+			"/News/Blogs", "/News/zeta", "/Alpha", "/News/alpha"}},
+		{"updated", true, []string{
+			"/News/alpha", "/Alpha", "/News/zeta", "/News/Blogs"}},
+		// Blogs has its feeds' 1 + 2 unread.
+		{"unread", false, []string{
+			"/News/alpha", "/News/zeta", "/News/Blogs", "/Alpha"}},
+		{"unread", true, []string{
+			"/Alpha", "/News/Blogs", "/News/zeta", "/News/alpha"}},
+		{"none", false, []string{
+			"/News/zeta", "/News/Blogs", "/Alpha", "/News/alpha"}},
+		{"none", true, []string{
+			"/News/alpha", "/Alpha", "/News/Blogs", "/News/zeta"}},
+	}
+	for _, test := range tests {
+		matches := append([]findMatch(nil), found...)
+		sortMatches(matches, test.key, test.reverse, counters, feedByID)
+		var got []string
+		for _, match := range matches {
+			got = append(got, match.path)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("sortMatches by %s, reverse: %v: got %q, want %q",
+				test.key, test.reverse, got, test.want)
+		}
+	}
+}
+
+func TestFindSorts(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(
+			catItem(1, "News", feedItem(10, "zeta"), feedItem(11, "Go")),
+			catItem(2, "Blogs", feedItem(12, "alpha"))),
+	})
+
+	tests := []struct {
+		args []string
+		want string
+		code int
+	}{
+		{nil, "/\n/Blogs\n/Blogs/alpha\n/News\n/News/Go\n/News/zeta\n",
+			EX_SUCCESS},
+		{[]string{"-type", "f", "-sort", "name"},
+			"/Blogs/alpha\n/News/Go\n/News/zeta\n", EX_SUCCESS},
+		{[]string{"-type", "f", "-sort", "none", "-reverse"},
+			"/Blogs/alpha\n/News/Go\n/News/zeta\n", EX_SUCCESS},
+		{[]string{"-sort", "size"}, "", EX_USAGE},
+	}
+	for _, test := range tests {
+		args := append([]string{"find"}, test.args...)
+		stdout, stderr, code := runTool(t, stub, "", args...)
+		if code != test.code || stdout != test.want {
+			t.Errorf("%q: got exit %d, stdout %q, stderr %q; "+
+				"want exit %d, %q", args, code, stdout, stderr, test.code,
+				test.want)
+		}
+	}
+}
+
+func TestFindLimit(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(
+			catItem(1, "News", feedItem(10, "zeta"), feedItem(11, "Go")),
+			catItem(2, "Blogs", feedItem(12, "alpha"))),
+	})
+
+	tests := []struct {
+		args       []string
+		want, note string
+	}{
+		{[]string{"-limit", "2"}, "/Blogs/alpha\n/News/Go\n",
+			"…(truncated, 1 more)\n"},
+		{[]string{"-limit", "2", "-sort", "none"},
+			"/News/zeta\n/News/Go\n", "…(truncated, 1 more)\n"},
+		{[]string{"-limit", "2", "-sort", "none", "-reverse"},
+			"/Blogs/alpha\n/News/Go\n", "…(truncated, 1 more)\n"},
+		{[]string{"-limit", "3"},
+			"/Blogs/alpha\n/News/Go\n/News/zeta\n", ""},
+	}
+	for _, test := range tests {
+		args := append([]string{"find", "/News", "/Blogs/alpha", "-type",
+			"f"}, test.args...)
+		stdout, stderr, code := runTool(t, stub, "", args...)
+		if code != EX_SUCCESS || stdout != test.want ||
+			stderr != test.note {
+			t.Errorf("%q: got exit %d, stdout %q, stderr %q; "+
+				"want %q, %q", args, code, stdout, stderr, test.want,
+				test.note)
+		}
+	}
+}
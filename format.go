@@ -0,0 +1,141 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"strings"
+	"text/template"
+	"time"
+	"ttrss"
+)
+
+// parseFormat parses text, as given to --format, as a text/template to
+// print each item with, checking it against samples, zero values of the
+// types the template may be given. It need only suit one of them.
+// Since shells make tabs and newlines awkward to type, the escapes \t, \n,
+// and \\ stand for them, outside actions. Within them, string literals have
+// Go's own escapes.
+func parseFormat(text string, samples ...interface{}) (*template.Template,
+	error) {
+	text = unescapeFormat(text)
+	tmpl, err := template.New("format").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// A misspelled field only shows up on execution; better now than after
+	// half the listing.
+	for _, sample := range samples {
+		if err = tmpl.Execute(ioutil.Discard, sample); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// formatEscapes replaces the escapes --format allows outside actions.
+var formatEscapes = strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n")
+
+// unescapeFormat replaces the escapes in text, as given to --format, leaving
+// its actions as they are.
+func unescapeFormat(text string) string {
+	var out strings.Builder
+	for {
+		start := strings.Index(text, "{{")
+		if start < 0 {
+			out.WriteString(formatEscapes.Replace(text))
+			return out.String()
+		}
+		out.WriteString(formatEscapes.Replace(text[:start]))
+		end := actionEnd(text, start+len("{{"))
+		out.WriteString(text[start:end])
+		text = text[end:]
+	}
+}
+
+// actionEnd returns the index in text just past the }} ending the action
+// whose insides start at from, skipping over quoted strings and comments,
+// which may hold }} of their own. An action left open runs to the end.
+func actionEnd(text string, from int) int {
+	var quote byte
+	for i := from; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case strings.HasPrefix(text[i:], "/*"):
+			end := strings.Index(text[i+len("/*"):], "*/")
+			if end < 0 {
+				return len(text)
+			}
+			i += len("/*") + end + len("*/") - 1
+		case strings.HasPrefix(text[i:], "}}"):
+			return i + len("}}")
+		}
+	}
+	return len(text)
+}
+
+// printFormatted prints data as tmpl says, then ends the line, unless the
+// template already did.
+func printFormatted(w io.Writer, tmpl *template.Template,
+	data interface{}) error {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return err
+	}
+	text := out.String()
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	_, err := io.WriteString(w, text)
+	return err
+}
+
+// HeadlineView is an article as --format templates see it, for cat, search,
+// and ls --articles. Text is the article's content as plain text, and is only
+// there with -f. Strings are made safe for the terminal, as usual.
+type HeadlineView struct {
+	ID        int
+	Title     string
+	Link      string
+	Author    string
+	Updated   time.Time
+	FeedID    int
+	FeedTitle string
+	Unread    bool
+	Marked    bool
+	Published bool
+	Score     int
+	Text      string
+}
+
+func viewHeadline(h ttrss.Headline) HeadlineView {
+	view := HeadlineView{
+		ID:        h.ID,
+		Title:     display(h.Title),
+		Link:      display(h.Link),
+		Author:    display(h.Author),
+		Updated:   h.Updated,
+		FeedID:    h.FeedID,
+		FeedTitle: display(h.FeedTitle),
+		Unread:    h.Unread,
+		Marked:    h.Marked,
+		Published: h.Published,
+		Score:     h.Score,
+	}
+	if h.Content != "" {
+		view.Text = displayLines(htmlToText(h.Content))
+	}
+	return view
+}
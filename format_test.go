@@ -0,0 +1,49 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFormat(t *testing.T) {
+	type item struct {
+		ID    int
+		Title string
+	}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{`{{.ID}}\t{{.Title}}`, "7\tGo"},
+		{`{{.ID}}\n{{.Title}}\n`, "7\nGo\n"},
+		{`a\\t`, `a\t`},
+		{`\x`, `\x`},
+		{`{{printf "%d\n" .ID}}`, "7\n"},
+		{`{{printf "%s\t%d" .Title .ID}}\t|`, "Go\t7\t|"},
+		{`{{printf "\\"}}\n`, "\\\n"},
+		{"{{printf `\\n`}}\\n", "\\n\n"},
+		{`{{"}}\n"}}\t`, "}}\n\t"},
+		{`{{printf "%c" '\t'}}\n`, "\t\n"},
+		{`{{/* }} \n */}}\t{{.ID}}`, "\t7"},
+	}
+	for _, test := range tests {
+		tmpl, err := parseFormat(test.format, item{})
+		if err != nil {
+			t.Errorf("parseFormat(%q): %v", test.format, err)
+			continue
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, item{7, "Go"}); err != nil ||
+			out.String() != test.want {
+			t.Errorf("parseFormat(%q): got %q (%v), want %q", test.format,
+				out.String(), err, test.want)
+		}
+	}
+
+	if _, err := parseFormat(`{{.Missing}}`, item{}); err == nil {
+		t.Errorf("parseFormat(%q): got no error for a missing field",
+			`{{.Missing}}`)
+	}
+}
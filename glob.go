@@ -0,0 +1,79 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"path"
+	"strings"
+	"ttrss"
+)
+
+// CatPathMatch is an item matched by a catpath pattern, and its catpath.
+type CatPathMatch struct {
+	Path string
+	Item *ttrss.FeedTreeItem
+}
+
+// hasGlob reports whether catpath contains any of the wildcards understood
+// by path.Match, and so may need expanding with GlobCatPath. Names can
+// contain them too, as in "[Blog] Foo", so callers should only expand
+// a catpath that doesn't resolve as it stands.
+func hasGlob(catpath string) bool {
+	return strings.ContainsAny(catpath, "*?[")
+}
+
+// GlobCatPath finds every category and feed matching pattern, a catpath
+// whose components may use path.Match wildcards, as in "/News/*/Go*".
+// As with ResolveCatPath, a trailing slash rules out feeds.
+// Matches come in tree order. No match at all is not an error.
+func GlobCatPath(pattern string) (matches []CatPathMatch, err error) {
+	verbosef("expanding %q", pattern)
+	tree, err := tt.GetFeedTree(true)
+	if err != nil {
+		return
+	}
+
+	wantCategory := strings.HasSuffix(pattern, "/") &&
+		!strings.HasSuffix(pattern, "\\/")
+	matches = globItems(&tree, "", PathComponents(pattern), wantCategory)
+	return
+}
+
+// globItems matches parts against the items within cat, found at catpath,
+// and what lies below them.
+func globItems(cat *ttrss.FeedTreeItem, catpath string, parts []string,
+	wantCategory bool) (matches []CatPathMatch) {
+	if len(parts) == 0 {
+		if catpath == "" {
+			catpath = "/"
+		}
+		return []CatPathMatch{{catpath, cat}}
+	}
+
+	last := len(parts) == 1
+	for i := range cat.Items {
+		child := &cat.Items[i]
+		if child.Type != ttrss.Category && (!last || wantCategory) {
+			continue
+		}
+		if !globMatch(parts[0], child.Name) {
+			continue
+		}
+		childPath := catpath + "/" + ttrss.EscapePathComponent(child.Name)
+		matches = append(matches,
+			globItems(child, childPath, parts[1:], wantCategory)...)
+	}
+	return
+}
+
+// globMatch reports whether name matches pattern, a single catpath
+// component. Unlike path.Match, a wildcard matches slashes in names.
+// A malformed pattern matches nothing.
+func globMatch(pattern, name string) bool {
+	// No name contains a NUL, so it can safely stand in for the slash.
+	const slash = "\x00"
+	pattern = strings.Replace(pattern, "/", slash, -1)
+	name = strings.Replace(name, "/", slash, -1)
+	matched, err := path.Match(pattern, name)
+	return err == nil && matched
+}
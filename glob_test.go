@@ -0,0 +1,39 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import "testing"
+
+func TestLiteralNamesWithWildcards(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News",
+			feedItem(10, "[Blog] Foo"),
+			feedItem(11, "B Foo"),
+			feedItem(12, "[Blog] Bar"),
+			feedItem(13, "Star*"))),
+	})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		// Names that resolve as they stand are taken literally.
+		{[]string{"ls", "/News/[Blog] Foo"}, "/News/[Blog] Foo\n"},
+		{[]string{"ls", "/News/Star*"}, "/News/Star*\n"},
+		{[]string{"rm", "--dry-run", "/News/[Blog] Foo"},
+			"would remove /News/[Blog] Foo\n"},
+		// Otherwise, they're patterns.
+		{[]string{"ls", "/News/[B]*"}, "/News/B Foo\n"},
+		{[]string{"ls", `/News/\[Blog\] *`},
+			"/News/[Blog] Bar\n/News/[Blog] Foo\n"},
+		{[]string{"rm", "--dry-run", "/News/[B]*"},
+			"would remove /News/B Foo\n"},
+	}
+	for _, test := range tests {
+		stdout, stderr, code := runTool(t, stub, "", test.args...)
+		if code != EX_SUCCESS || stdout != test.want {
+			t.Errorf("%q: got exit %d, stdout %q, stderr %q; want %q",
+				test.args, code, stdout, stderr, test.want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+	"ttrss"
+)
+
+// EX_NOMATCH is what grep exits with when nothing matched, as grep(1) does.
+const EX_NOMATCH = 1
+
+type Grep struct {
+	flHelp       bool
+	flRecurse    bool
+	flIgnoreCase bool
+	flFilesOnly  bool
+	flCount      bool
+	flContent    bool
+	flFormat     string
+	flOutput     string
+	flags        flag.FlagSet
+}
+
+func (grep *Grep) Init() {
+	grep.flags.Init("grep", flag.PanicOnError)
+
+	grep.flags.BoolVar(&grep.flHelp, "h", false, "help")
+	grep.flags.BoolVar(&grep.flHelp, "help", false, "help")
+
+	recurseUsage := "search categories, and everything in them"
+	grep.flags.BoolVar(&grep.flRecurse, "r", false, recurseUsage)
+	grep.flags.BoolVar(&grep.flRecurse, "R", false, recurseUsage)
+	grep.flags.BoolVar(&grep.flIgnoreCase, "i", false, "ignore case")
+	grep.flags.BoolVar(&grep.flFilesOnly, "l", false,
+		"print only the catpath of each feed with a match")
+	countUsage := "print only how many articles match in each feed, " +
+		"and a total"
+	grep.flags.BoolVar(&grep.flCount, "c", false, countUsage)
+	grep.flags.BoolVar(&grep.flCount, "count", false, countUsage)
+	grep.flags.BoolVar(&grep.flContent, "content", false,
+		"search article content as well as titles")
+	grep.flags.StringVar(&grep.flFormat, "format", "",
+		"print each matching article using the Go text/template `TEMPLATE`")
+	grep.flags.StringVar(&grep.flOutput, "o", "", outputUsage)
+}
+
+func (grep *Grep) Flags() *flag.FlagSet {
+	return &grep.flags
+}
+
+func (grep *Grep) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "grep [-cilR] [--content] [--format TEMPLATE] [-o FILE] "+
+		"pattern catpath... -- search articles")
+}
+
+// Run prints the title and link of each article in the feeds named whose
+// title (or, with --content, content) matches the regular expression
+// pattern.
+// With --format, each article is printed as the template says, as for cat.
+// With -c, only the number of matches in each feed is printed, as du
+// prints unread counts, and then the total.
+// With -o, what it prints goes to a file instead of stdout.
+// As with grep(1), it exits 0 if anything matched, and EX_NOMATCH if not.
+// It carries on past bad catpaths, exiting EX_NOINPUT or EX_DATAERR for the
+// last one; if the server fails, it gives up with EX_UNAVAILABLE.
+func (grep *Grep) Run(args []string) {
+	grep.flags.Parse(args)
+
+	if grep.flHelp {
+		flagSetPrintUsage(grep.flags, os.Stdout, "grep")
+		exit(EX_SUCCESS)
+	}
+
+	exclusive := 0
+	for _, set := range []bool{
+		grep.flFilesOnly, grep.flCount, grep.flFormat != ""} {
+		if set {
+			exclusive++
+		}
+	}
+	if grep.flags.NArg() < 2 || exclusive > 1 {
+		flagSetPrintUsage(grep.flags, os.Stderr, "grep")
+		exit(EX_USAGE)
+	}
+
+	var format *template.Template
+	if grep.flFormat != "" {
+		var err error
+		format, err = parseFormat(grep.flFormat, HeadlineView{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "grep: bad --format:", err)
+			exit(EX_USAGE)
+		}
+	}
+
+	expr := grep.flags.Arg(0)
+	if grep.flIgnoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "grep:", err)
+		exit(EX_USAGE)
+	}
+	outputTo(grep.flOutput)
+
+	code := EX_NOMATCH
+	failed := false
+	matchesByFeed := make(map[int]int)
+	for _, catpath := range grep.flags.Args()[1:] {
+		item, err := ResolveCatPath(catpath)
+		if err == nil && item.Type == ttrss.Category && !grep.flRecurse {
+			err = fmt.Errorf("%q is a category (use -R)", catpath)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "grep:", err)
+			printCandidates(err)
+			failed = true
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		err = grep.eachHeadline(item, func(h ttrss.Headline) {
+			if !grep.matches(pattern, h) {
+				return
+			}
+			if !failed {
+				code = EX_SUCCESS
+			}
+			if grep.flFilesOnly || grep.flCount {
+				matchesByFeed[h.FeedID]++
+				return
+			}
+			if format == nil {
+				fmt.Printf("%s\t%s\n", display(h.Title), display(h.Link))
+				return
+			}
+			if err := printFormatted(os.Stdout, format,
+				viewHeadline(h)); err != nil {
+				fmt.Fprintln(os.Stderr, "grep:", err)
+				exit(EX_DATAERR)
+			}
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "grep: %s: %v\n", catpath, err)
+			exit(EX_UNAVAILABLE)
+		}
+	}
+
+	total := 0
+	for _, n := range matchesByFeed {
+		total += n
+	}
+	if (grep.flFilesOnly || grep.flCount) && len(matchesByFeed) > 0 {
+		err := printFeedPaths(matchesByFeed, grep.flCount)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "grep:", err)
+			exit(EX_UNAVAILABLE)
+		}
+	}
+	if grep.flCount {
+		fmt.Printf("%d\ttotal\n", total)
+	}
+	exit(code)
+}
+
+func (grep *Grep) matches(pattern *regexp.Regexp, h ttrss.Headline) bool {
+	if pattern.MatchString(h.Title) {
+		return true
+	}
+	// Match what the reader sees, not the markup.
+	return grep.flContent && pattern.MatchString(htmlToText(h.Content))
+}
+
+// eachHeadline calls fn on every article the server has in item, newest
+// first, a page at a time.
+func (grep *Grep) eachHeadline(item *ttrss.FeedTreeItem,
+	fn func(ttrss.Headline)) error {
+	req := headlinesRequestFor(item)
+	req.ShowContent = grep.flContent
+	return eachHeadline(req, fn)
+}
+
+// printFeedPaths prints the catpath of each feed whose ID is in counts, in
+// tree order, after its count if withCounts is set.
+func printFeedPaths(counts map[int]int, withCounts bool) error {
+	tree, err := tt.GetFeedTree(true)
+	if err != nil {
+		return err
+	}
+
+	walkCatPath(&tree, "/", func(item *ttrss.FeedTreeItem,
+		catpath string) bool {
+		n, ok := counts[item.ID]
+		if item.Type != ttrss.Feed || !ok {
+			return true
+		}
+		if withCounts {
+			fmt.Printf("%d\t%s\n", n, display(catpath))
+		} else {
+			fmt.Println(display(catpath))
+		}
+		// Virtual feeds can appear twice; print them once.
+		delete(counts, item.ID)
+		return true
+	})
+	return nil
+}
+
+// walkCatPath calls fn on item, found at catpath, and then on everything
+// below it, in tree order, along with their catpaths. If fn returns false
+// for a category, what's in it is skipped.
+func walkCatPath(item *ttrss.FeedTreeItem, catpath string,
+	fn func(item *ttrss.FeedTreeItem, catpath string) bool) {
+	if !fn(item, catpath) {
+		return
+	}
+	for i := range item.Items {
+		child := &item.Items[i]
+		childPath := strings.TrimSuffix(catpath, "/") + "/" +
+			ttrss.EscapePathComponent(child.Name)
+		walkCatPath(child, childPath, fn)
+	}
+}
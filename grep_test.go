@@ -0,0 +1,112 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import "testing"
+
+// headlinesOp answers getHeadlines with headlines, made by headlineItem, on
+// the first page, and nothing after.
+func headlinesOp(headlines ...interface{}) stubOp {
+	return func(req map[string]interface{}) interface{} {
+		if skip, _ := req["skip"].(float64); skip > 0 {
+			return []interface{}{}
+		}
+		return headlines
+	}
+}
+
+func TestGrepFilesOnlyNested(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News",
+			feedItem(10, "A"),
+			catItem(2, "Sub", feedItem(12, "C")),
+			feedItem(11, "B"))),
+		"getHeadlines": headlinesOp(
+			headlineItem(103, 11, "go in B"),
+			headlineItem(102, 12, "go in C"),
+			headlineItem(101, 12, "more go in C"),
+			headlineItem(100, 10, "go in A")),
+	})
+
+	stdout, stderr, code := runTool(t, stub, "", "grep", "-l", "-R", "go",
+		"/News")
+	want := "/News/A\n/News/Sub/C\n/News/B\n"
+	if code != EX_SUCCESS || stdout != want {
+		t.Errorf("grep -l -R: got exit %d, stdout %q, stderr %q; want %q",
+			code, stdout, stderr, want)
+	}
+}
+
+func TestGrepFormatIntoMark(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News",
+			feedItem(10, "A"), feedItem(11, "B"))),
+		"getHeadlines": headlinesOp(
+			headlineItem(103, 11, "go in B"),
+			headlineItem(102, 11, "rust in B"),
+			headlineItem(100, 10, "go in A")),
+		"updateArticle": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"status": "OK", "updated": 2}
+		},
+	})
+
+	stdout, stderr, code := runTool(t, stub, "", "grep", "--format",
+		"{{.ID}}", "-R", "go", "/News")
+	if code != EX_SUCCESS || stdout != "103\n100\n" {
+		t.Fatalf("grep --format: got exit %d, stdout %q, stderr %q; "+
+			"want 103 and 100", code, stdout, stderr)
+	}
+
+	_, stderr, code = runTool(t, stub, stdout, "mark", "read", "-")
+	calls := stub.called("updateArticle")
+	if code != EX_SUCCESS || len(calls) != 1 ||
+		calls[0].Req["article_ids"] != "103,100" {
+		t.Errorf("mark read - after grep: got exit %d, stderr %q, "+
+			"updateArticle calls %+v; want one for 103,100",
+			code, stderr, calls)
+	}
+
+	_, _, code = runTool(t, stub, "", "grep", "-l", "--format", "{{.ID}}",
+		"-R", "go", "/News")
+	if code != EX_USAGE {
+		t.Errorf("grep -l --format: got exit %d, want EX_USAGE", code)
+	}
+}
+
+func TestGrepCount(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News",
+			feedItem(10, "A"),
+			catItem(2, "Sub", feedItem(12, "C")),
+			feedItem(11, "B"))),
+		"getHeadlines": headlinesOp(
+			headlineItem(104, 11, "go in B"),
+			headlineItem(103, 12, "go in C"),
+			headlineItem(102, 12, "more go in C"),
+			headlineItem(101, 12, "rust in C"),
+			headlineItem(100, 10, "rust in A")),
+	})
+
+	for _, flag := range []string{"-c", "--count"} {
+		stdout, stderr, code := runTool(t, stub, "", "grep", flag, "-R",
+			"go", "/News")
+		want := "2\t/News/Sub/C\n1\t/News/B\n3\ttotal\n"
+		if code != EX_SUCCESS || stdout != want {
+			t.Errorf("grep %s: got exit %d, stdout %q, stderr %q; want %q",
+				flag, code, stdout, stderr, want)
+		}
+	}
+
+	stdout, _, code := runTool(t, stub, "", "grep", "-c", "-R", "perl",
+		"/News")
+	if code != EX_NOMATCH || stdout != "0\ttotal\n" {
+		t.Errorf("grep -c without a match: got exit %d, stdout %q; "+
+			"want exit %d and a total of 0", code, stdout, EX_NOMATCH)
+	}
+
+	_, _, code = runTool(t, stub, "", "grep", "-c", "-l", "-R", "go",
+		"/News")
+	if code != EX_USAGE {
+		t.Errorf("grep -c -l: got exit %d, want EX_USAGE", code)
+	}
+}
@@ -0,0 +1,70 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+)
+
+type Head struct {
+	flHelp  bool
+	flCount int
+	flags   flag.FlagSet
+}
+
+func (head *Head) Init() {
+	head.flags.Init("head", flag.PanicOnError)
+
+	head.flags.BoolVar(&head.flHelp, "h", false, "help")
+	head.flags.BoolVar(&head.flHelp, "help", false, "help")
+
+	head.flags.IntVar(&head.flCount, "n", 10,
+		"print the `N` newest articles")
+}
+
+func (head *Head) Flags() *flag.FlagSet {
+	return &head.flags
+}
+
+func (head *Head) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "head [-n N] catpath"+
+		" -- print a feed or category's newest articles")
+}
+
+// Run prints the newest articles in a feed or category, newest first: the
+// top of what cat prints, as tail prints the bottom.
+func (head *Head) Run(args []string) {
+	head.flags.Parse(args)
+
+	if head.flHelp {
+		flagSetPrintUsage(head.flags, os.Stdout, "head")
+		exit(EX_SUCCESS)
+	}
+
+	if head.flags.NArg() != 1 || head.flCount <= 0 {
+		flagSetPrintUsage(head.flags, os.Stderr, "head")
+		exit(EX_USAGE)
+	}
+
+	catpath := head.flags.Arg(0)
+	item, err := ResolveCatPath(catpath)
+	if err != nil {
+		printCandidates(err)
+		log.Fatalln(err)
+	}
+
+	req := headlinesRequestFor(item)
+	req.Limit = head.flCount
+	newest, err := tt.GetHeadlines(req)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for _, h := range newest {
+		printHeadline(os.Stdout, h)
+	}
+}
@@ -0,0 +1,139 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"html"
+	"strings"
+)
+
+// blockTags start a new line when rendered as plain text.
+var blockTags = map[string]bool{
+	"address": true, "article": true, "blockquote": true, "br": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "figcaption": true,
+	"figure": true, "footer": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "tr": true, "ul": true,
+}
+
+// htmlToText renders an article's HTML as plain text, good enough for
+// reading in a pager: tags are dropped, block elements start new lines, list
+// items are bulleted, and entities are decoded. Scripts, styles, and
+// comments vanish entirely.
+// This is no HTML parser. It doesn't need to be: the server has already
+// sanitized what it sends.
+func htmlToText(content string) string {
+	var b strings.Builder
+	rest := content
+	for rest != "" {
+		lt := strings.IndexByte(rest, '<')
+		if lt < 0 {
+			writeHTMLText(&b, rest)
+			break
+		}
+		writeHTMLText(&b, rest[:lt])
+		rest = rest[lt:]
+
+		if strings.HasPrefix(rest, "<!--") {
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				break
+			}
+			rest = rest[end+len("-->"):]
+			continue
+		}
+
+		gt := strings.IndexByte(rest, '>')
+		if gt < 0 {
+			// Not a tag after all.
+			writeHTMLText(&b, rest)
+			break
+		}
+		tag := rest[1:gt]
+		rest = rest[gt+1:]
+
+		closing := strings.HasPrefix(tag, "/")
+		name := strings.ToLower(strings.TrimLeft(tag, "/"))
+		if i := strings.IndexAny(name, " \t\n/"); i >= 0 {
+			name = name[:i]
+		}
+
+		switch {
+		case (name == "script" || name == "style") && !closing:
+			end := strings.Index(strings.ToLower(rest), "</"+name)
+			if end < 0 {
+				rest = ""
+			} else {
+				rest = rest[end:]
+			}
+		case name == "li":
+			if !closing {
+				endLines(&b, 1)
+				b.WriteString("- ")
+			}
+		case name == "p" || blockTags[name] && strings.HasPrefix(name, "h"):
+			endLines(&b, 2)
+		case blockTags[name]:
+			endLines(&b, 1)
+		}
+	}
+	return tidyLines(b.String())
+}
+
+// writeHTMLText writes text from between tags to b, with runs of white space
+// collapsed, as a browser would.
+func writeHTMLText(b *strings.Builder, text string) {
+	text = html.UnescapeString(text)
+	spaced := strings.Join(strings.Fields(text), " ")
+	if spaced == "" {
+		if text != "" {
+			writeSpace(b)
+		}
+		return
+	}
+	if strings.TrimLeft(text[:1], " \t\r\n") == "" {
+		writeSpace(b)
+	}
+	b.WriteString(spaced)
+	if strings.TrimRight(text[len(text)-1:], " \t\r\n") == "" {
+		b.WriteString(" ")
+	}
+}
+
+// writeSpace writes a space to b, unless it already ends with one.
+func writeSpace(b *strings.Builder) {
+	if !strings.HasSuffix(b.String(), " ") {
+		b.WriteString(" ")
+	}
+}
+
+// endLines ends b with at least n newlines, so that what's written next
+// starts a line, or with n of 2, follows a blank line. Spaces at the end of
+// a line don't count against it.
+func endLines(b *strings.Builder, n int) {
+	text := strings.TrimRight(b.String(), " ")
+	have := len(text) - len(strings.TrimRight(text, "\n"))
+	for ; have < n; have++ {
+		b.WriteString("\n")
+	}
+}
+
+// tidyLines trims each line, and squeezes runs of blank lines down to one.
+func tidyLines(text string) string {
+	var lines []string
+	blank := true
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,59 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name, html, want string
+	}{
+		{"plain", "Just text", "Just text"},
+		{"white space", "  Lots \n\t of   space  ", "Lots of space"},
+		{"inline", "Some <b>bold</b> and <a href=\"x\">a link</a>.",
+			"Some bold and a link."},
+		{"paragraphs", "<p>One</p><p>Two</p>", "One\n\nTwo"},
+		{"headings", "<h1>Title</h1>Body", "Title\n\nBody"},
+		{"br", "Line one<br>Line two<br/>Line three",
+			"Line one\nLine two\nLine three"},
+		{"divs", "<div>A</div><div>B</div>", "A\nB"},
+		{"blank lines squeezed", "<p>A</p><p></p><p></p><div>B</div>",
+			"A\n\nB"},
+		{"list", "<ul><li>One</li><li>Two <i>too</i></li></ul>",
+			"- One\n- Two too"},
+		{"upper case", "<P>A</P><LI>B", "A\n\n- B"},
+		{"attributes", "<p class=\"x\">A</p><br class='y'/>B", "A\n\nB"},
+		{"script", "A<script>var x = \"<p>\";</script>B", "AB"},
+		{"style", "A <STYLE>p { color: red }</STYLE> B", "A B"},
+		{"unclosed script", "A<script>never ends", "A"},
+		{"entities", "Fish &amp; chips &lt;3 &quot;q&quot; &#233;t&eacute;",
+			"Fish & chips <3 \"q\" été"},
+		{"escaped tag", "&lt;p&gt; stays", "<p> stays"},
+		{"comment", "A<!-- <p>hidden</p> -->B", "AB"},
+		{"unclosed comment", "A<!-- never ends", "A"},
+		{"not a tag", "1 < 2", "1 < 2"},
+	}
+	for _, test := range tests {
+		if got := htmlToText(test.html); got != test.want {
+			t.Errorf("%s: htmlToText(%q) = %q, want %q", test.name,
+				test.html, got, test.want)
+		}
+	}
+}
+
+func TestTidyLines(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"", ""},
+		{"  a  \n  b  ", "a\nb"},
+		{"\n\n\na\n\n\n\nb\n\n", "a\n\nb"},
+		{"a\n \t \nb", "a\n\nb"},
+	}
+	for _, test := range tests {
+		if got := tidyLines(test.text); got != test.want {
+			t.Errorf("tidyLines(%q) = %q, want %q", test.text, got,
+				test.want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+	"ttrss"
+)
+
+// jsonFeedVersion identifies the JSON Feed spec followed, as the spec asks.
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
+
+// Just enough of JSON Feed to describe a list of articles.
+type jsonFeed struct {
+	Version string         `json:"version"`
+	Title   string         `json:"title"`
+	Items   []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID           string           `json:"id"`
+	URL          string           `json:"url,omitempty"`
+	Title        string           `json:"title,omitempty"`
+	ContentHTML  string           `json:"content_html,omitempty"`
+	DateModified string           `json:"date_modified,omitempty"`
+	Authors      []jsonFeedAuthor `json:"authors,omitempty"`
+}
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+// writeJSONFeed writes headlines to w as a JSON Feed titled title.
+func writeJSONFeed(w io.Writer, title string,
+	headlines []ttrss.Headline) error {
+	feed := jsonFeed{
+		Version: jsonFeedVersion,
+		Title:   title,
+		// Even an empty feed has items.
+		Items: make([]jsonFeedItem, 0, len(headlines)),
+	}
+	for _, h := range headlines {
+		item := jsonFeedItem{
+			ID:          strconv.Itoa(h.ID),
+			URL:         h.Link,
+			Title:       h.Title,
+			ContentHTML: h.Content,
+		}
+		if !h.Updated.IsZero() {
+			item.DateModified = h.Updated.UTC().Format(time.RFC3339)
+		}
+		if h.Author != "" {
+			item.Authors = []jsonFeedAuthor{{h.Author}}
+		}
+		feed.Items = append(feed.Items, item)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(feed)
+}
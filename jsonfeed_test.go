@@ -0,0 +1,65 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+	"ttrss"
+)
+
+func TestWriteJSONFeed(t *testing.T) {
+	headlines := []ttrss.Headline{
+		{ID: 7, Title: "Fish & chips", Link: "http://a.example/7",
+			Author: "Alice", FeedTitle: "Food",
+			Updated: time.Date(2013, 5, 1, 13, 0, 0, 0,
+				time.FixedZone("", 3600)),
+			Content: "<p>Vinegar</p>"},
+		{ID: 8, FeedTitle: "Weather"},
+	}
+
+	var b bytes.Buffer
+	if err := writeJSONFeed(&b, "Unread", headlines); err != nil {
+		t.Fatal(err)
+	}
+
+	var got jsonFeed
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("output does not parse: %v\n%s", err, b.String())
+	}
+	want := jsonFeed{
+		Version: "https://jsonfeed.org/version/1.1",
+		Title:   "Unread",
+		Items: []jsonFeedItem{
+			{ID: "7", URL: "http://a.example/7", Title: "Fish & chips",
+				ContentHTML:  "<p>Vinegar</p>",
+				DateModified: "2013-05-01T12:00:00Z",
+				Authors:      []jsonFeedAuthor{{"Alice"}}},
+			{ID: "8"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v\nwant %+v", got, want)
+	}
+	if bytes.Contains(b.Bytes(), []byte(`\u0026`)) {
+		t.Errorf("HTML escaped in output:\n%s", b.String())
+	}
+}
+
+func TestWriteJSONFeedEmpty(t *testing.T) {
+	var b bytes.Buffer
+	if err := writeJSONFeed(&b, "Nothing", nil); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("output does not parse: %v\n%s", err, b.String())
+	}
+	items, ok := got["items"].([]interface{})
+	if !ok || len(items) != 0 {
+		t.Errorf("items = %#v, want an empty list", got["items"])
+	}
+}
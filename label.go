@@ -0,0 +1,230 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"ttrss"
+)
+
+type Label struct {
+	flHelp bool
+	flags  flag.FlagSet
+}
+
+func (label *Label) Init() {
+	label.flags.Init("label", flag.PanicOnError)
+
+	label.flags.BoolVar(&label.flHelp, "h", false, "help")
+	label.flags.BoolVar(&label.flHelp, "help", false, "help")
+}
+
+func (label *Label) Flags() *flag.FlagSet {
+	return &label.flags
+}
+
+func (label *Label) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "label ls [article_id] -- list labels, or an article's")
+	fmt.Fprintln(w, "label add|rm name -- create or delete a label")
+	fmt.Fprintln(w, "label assign|clear name article_id... "+
+		"-- label articles, or unlabel them")
+}
+
+// Run lists, creates, or deletes labels, or labels or unlabels articles, as
+// its first argument says. Article IDs are as for star, "-" and all.
+// It exits EX_NOINPUT if there's no label by the name given, EX_DATAERR if
+// there already is one to add or an ID is malformed, and EX_UNAVAILABLE if
+// the server refused. Adding and removing labels need a server plugin.
+func (label *Label) Run(args []string) {
+	label.flags.Parse(args)
+
+	if label.flHelp {
+		flagSetPrintUsage(label.flags, os.Stdout, "label")
+		exit(EX_SUCCESS)
+	}
+
+	argc := label.flags.NArg()
+	rest := label.flags.Args()
+	if argc > 0 {
+		rest = rest[1:]
+	}
+	switch label.flags.Arg(0) {
+	case "ls":
+		if argc <= 2 {
+			exit(listLabels(rest))
+		}
+	case "add":
+		if argc == 2 {
+			exit(addLabel(rest[0]))
+		}
+	case "rm":
+		if argc == 2 {
+			exit(removeLabel(rest[0]))
+		}
+	case "assign", "clear":
+		if argc >= 3 {
+			assign := label.flags.Arg(0) == "assign"
+			exit(setLabel(rest[0], rest[1:], assign))
+		}
+	}
+	flagSetPrintUsage(label.flags, os.Stderr, "label")
+	exit(EX_USAGE)
+}
+
+// listLabels prints the caption of each label, or if args gives an article
+// ID, of each label that article has, and returns the exit code for how that
+// went.
+func listLabels(args []string) int {
+	ids, err := parseArticleIDs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "label:", err)
+		return EX_DATAERR
+	}
+	articleID := 0
+	if len(ids) > 0 {
+		articleID = ids[0]
+	}
+
+	labels, err := tt.GetLabels(articleID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "label:", err)
+		return EX_UNAVAILABLE
+	}
+	for _, l := range labels {
+		if articleID == 0 || l.Checked {
+			fmt.Println(display(l.Caption))
+		}
+	}
+	return EX_SUCCESS
+}
+
+// findLabel returns the label captioned caption, ignoring case, or nil if
+// there's none. Should captions differ only in case, an exact match wins.
+func findLabel(caption string) (*ttrss.Label, error) {
+	labels, err := tt.GetLabels(0)
+	if err != nil {
+		return nil, err
+	}
+	var found *ttrss.Label
+	for i := range labels {
+		if labels[i].Caption == caption {
+			return &labels[i], nil
+		}
+		if found == nil && strings.EqualFold(labels[i].Caption, caption) {
+			found = &labels[i]
+		}
+	}
+	return found, nil
+}
+
+// mustFindLabel is findLabel for a label that should exist. If it doesn't,
+// or the server won't say, it complains and returns the exit code for that.
+func mustFindLabel(caption string) (found *ttrss.Label, code int) {
+	found, err := findLabel(caption)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "label:", err)
+		return nil, EX_UNAVAILABLE
+	}
+	if found == nil {
+		fmt.Fprintf(os.Stderr, "label: no label %q\n", caption)
+		return nil, EX_NOINPUT
+	}
+	return found, EX_SUCCESS
+}
+
+// addLabel creates a label captioned caption, printing its ID and caption,
+// and returns the exit code for how that went.
+func addLabel(caption string) int {
+	existing, err := findLabel(caption)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "label:", err)
+		return EX_UNAVAILABLE
+	}
+	if existing != nil {
+		fmt.Fprintf(os.Stderr, "label: label exists: %q\n",
+			existing.Caption)
+		return EX_DATAERR
+	}
+
+	id, err := tt.AddLabel(caption)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	logChange(ChangelogEntry{Op: "label add", ID: id, To: caption,
+		Result: result})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "label:", err)
+		return EX_UNAVAILABLE
+	}
+	fmt.Printf("%d\t%s\n", id, display(caption))
+	return EX_SUCCESS
+}
+
+// removeLabel deletes the label captioned caption, and returns the exit code
+// for how that went.
+func removeLabel(caption string) int {
+	found, code := mustFindLabel(caption)
+	if code != EX_SUCCESS {
+		return code
+	}
+
+	err := tt.RemoveLabel(found.ID)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	logChange(ChangelogEntry{Op: "label rm", ID: found.ID, Result: result})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "label:", err)
+		return EX_UNAVAILABLE
+	}
+	return EX_SUCCESS
+}
+
+// setLabel gives each article in args the label captioned caption, or if
+// assign is false, takes it away, and returns the exit code for how that
+// went.
+func setLabel(caption string, args []string, assign bool) int {
+	ids, err := parseArticleIDs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "label:", err)
+		return EX_DATAERR
+	}
+	found, code := mustFindLabel(caption)
+	if code != EX_SUCCESS || len(ids) == 0 {
+		return code
+	}
+
+	err = tt.SetArticleLabel(ids, found.ID, assign)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected += len(ids)
+	}
+	op := "label clear"
+	if assign {
+		op = "label assign"
+	}
+	for _, id := range ids {
+		logChange(ChangelogEntry{Op: op, ID: id, To: caption,
+			Result: result})
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "label:", err)
+		return EX_UNAVAILABLE
+	}
+	return EX_SUCCESS
+}
@@ -0,0 +1,68 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func labelsOp(captions ...string) stubOp {
+	return func(map[string]interface{}) interface{} {
+		labels := make([]interface{}, len(captions))
+		for i, caption := range captions {
+			labels[i] = map[string]interface{}{
+				"id": -1025 - i, "caption": caption}
+		}
+		return labels
+	}
+}
+
+func TestFindLabel(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getLabels": labelsOp("News", "later", "Later"),
+	})
+	useStub(t, stub)
+
+	tests := []struct {
+		caption string
+		want    int // 0 if there's no such label
+	}{
+		{"News", -1025},
+		{"news", -1025},
+		{"NEWS", -1025},
+		{"later", -1026},
+		{"Later", -1027},
+		{"LATER", -1026},
+		{"Old", 0},
+	}
+	for _, test := range tests {
+		found, err := findLabel(test.caption)
+		if err != nil {
+			t.Fatalf("findLabel(%q): %v", test.caption, err)
+		}
+		got := 0
+		if found != nil {
+			got = found.ID
+		}
+		if got != test.want {
+			t.Errorf("findLabel(%q) = label %d, want %d", test.caption,
+				got, test.want)
+		}
+	}
+}
+
+func TestLabelAddExistsIgnoringCase(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getLabels": labelsOp("News"),
+	})
+
+	_, stderr, code := runTool(t, stub, "", "label", "add", "news")
+	if code != EX_DATAERR || !strings.Contains(stderr, `"News"`) {
+		t.Errorf("label add news: exit %d, stderr %q; want %d naming News",
+			code, stderr, EX_DATAERR)
+	}
+	if calls := stub.called("addLabel"); len(calls) != 0 {
+		t.Errorf("label add news: got addLabel calls %+v", calls)
+	}
+}
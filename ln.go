@@ -0,0 +1,643 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"ttrss"
+)
+
+type Ln struct {
+	flHelp        bool
+	flRetryFetch  int
+	flFromFile    string
+	flParents     bool
+	flFeedUser    string
+	flFeedPass    string
+	flFeedPassCmd string
+	flPick        int
+	flDryRun      bool
+	flTitle       string
+	flIdempotent  bool
+	flJobs        int
+	flReplace     bool
+	flOnlyErrors  bool
+	flags         flag.FlagSet
+}
+
+func (ln *Ln) Init() {
+	ln.flags.Init("ln", flag.PanicOnError)
+
+	ln.flags.BoolVar(&ln.flHelp, "h", false, "help")
+	ln.flags.BoolVar(&ln.flHelp, "help", false, "help")
+
+	ln.flags.IntVar(&ln.flRetryFetch, "retry-fetch", 0,
+		"retry up to `N` times if the server could not fetch the feed")
+
+	fromFileUsage := "also subscribe to the URLs listed in `FILE` " +
+		"(- for stdin), one per line"
+	ln.flags.StringVar(&ln.flFromFile, "f", "", fromFileUsage)
+	ln.flags.StringVar(&ln.flFromFile, "from-file", "", fromFileUsage)
+
+	ln.flags.BoolVar(&ln.flParents, "p", false,
+		"create the category, and any above it, if missing")
+
+	ln.flags.StringVar(&ln.flFeedUser, "feed-user", "",
+		"log in to fetch the feeds as `USER`")
+	ln.flags.StringVar(&ln.flFeedPass, "feed-pass", "",
+		"with --feed-user, log in with `PASSWORD` (prompted for if not given)")
+	ln.flags.StringVar(&ln.flFeedPassCmd, "feed-pass-command", "",
+		"with --feed-user, log in with the password printed by `COMMAND`")
+
+	ln.flags.IntVar(&ln.flPick, "pick", 0,
+		"given a web page offering several feeds, subscribe to the `N`th")
+
+	ln.flags.BoolVar(&ln.flDryRun, "dry-run", false,
+		"fetch and check each feed, but subscribe to nothing")
+
+	ln.flags.StringVar(&ln.flTitle, "title", "",
+		"show the new feed under `TITLE` rather than its own")
+
+	ln.flags.BoolVar(&ln.flIdempotent, "idempotent", false,
+		"say nothing of feeds already subscribed to")
+
+	ln.flags.IntVar(&ln.flJobs, "jobs", 1,
+		"fetch and subscribe to up to `N` feeds at once")
+
+	ln.flags.BoolVar(&ln.flReplace, "replace", false,
+		"given a URL and a feed, move the feed to the URL")
+
+	ln.flags.BoolVar(&ln.flOnlyErrors, "only-errors", false,
+		"given several feeds, report only failures, and the final count")
+}
+
+func (ln *Ln) Flags() *flag.FlagSet {
+	return &ln.flags
+}
+
+func (ln *Ln) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "ln [-p] [--dry-run] [--idempotent] [--retry-fetch N] "+
+		"[--jobs N] [-f FILE] [--only-errors] "+
+		"[--feed-user USER "+
+		"[--feed-pass PASSWORD | --feed-pass-command COMMAND]] "+
+		"[--pick N] [--title TITLE] feed... "+
+		"[catpath] -- subscribe to new feeds")
+	fmt.Fprintln(w, "ln --replace [--dry-run] [--title TITLE] url catpath "+
+		"-- resubscribe to a feed that has moved")
+}
+
+// Run subscribes to each feed URL given, filing them all in the category
+// named last, or in Uncategorized if the last argument is a URL too.
+// It carries on past failures, and exits EX_DATAERR if there were any.
+// A feed already subscribed to is no failure, but is reported, unless
+// --idempotent or --only-errors says not to.
+// With -f, the URLs listed in a file are subscribed to as well.
+// With -p, the category is created first if need be, as mkdir -p would.
+// With --feed-user, the server logs in to fetch every feed given, with the
+// password given, printed by --feed-pass-command, or prompted for.
+// Given a web page rather than a feed, it subscribes to the feed the page
+// offers. If it offers several, it asks which, or with --pick, takes the Nth.
+// Given several URLs, or a list of them, it reports how each went, then
+// counts those subscribed to and those that failed. With --only-errors, it
+// reports only the failures before counting.
+// With --jobs, several feeds are fetched and subscribed to at once, but
+// reported on in order all the same.
+// With --title, the one feed given is renamed once subscribed to.
+// With --dry-run, it fetches each feed and sums it up instead, and neither
+// subscribes to it nor creates a category.
+// With --replace, it subscribes to the URL in place of the feed named, as
+// for a feed that has moved: see replace.
+func (ln *Ln) Run(args []string) {
+	ln.flags.Parse(args)
+
+	if ln.flHelp {
+		flagSetPrintUsage(ln.flags, os.Stdout, "ln")
+		exit(EX_SUCCESS)
+	}
+
+	argc := ln.flags.NArg()
+	promptForPass := ln.flFeedUser != "" && ln.flFeedPass == "" &&
+		ln.flFeedPassCmd == ""
+	if argc < 1 && ln.flFromFile == "" || ln.flPick < 0 || ln.flJobs < 1 ||
+		(ln.flFeedPass != "" || ln.flFeedPassCmd != "") &&
+			ln.flFeedUser == "" ||
+		ln.flFeedPass != "" && ln.flFeedPassCmd != "" ||
+		promptForPass && ln.flFromFile == "-" ||
+		ln.flReplace && (argc != 2 || ln.flFromFile != "" || ln.flParents) {
+		flagSetPrintUsage(ln.flags, os.Stderr, "ln")
+		exit(EX_USAGE)
+	}
+	if promptForPass {
+		pass, err := readPassword(os.Stdin, os.Stderr,
+			"feed password for "+ln.flFeedUser)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		ln.flFeedPass = pass
+	}
+	if ln.flFeedPassCmd != "" {
+		pass, err := passwordFromCommand(ln.flFeedPassCmd)
+		if err != nil {
+			log.Fatal(err.Error())
+		}
+		ln.flFeedPass = pass
+	}
+
+	if ln.flReplace {
+		exit(ln.replace(ln.flags.Arg(0), ln.flags.Arg(1)))
+	}
+
+	feeds := ln.flags.Args()
+	catpath := "/"
+	// With -f, even the only argument can be the catpath.
+	listed := argc > 1 || ln.flFromFile != ""
+	if argc > 0 && listed && !isFeedURL(feeds[argc-1]) {
+		feeds, catpath = feeds[:argc-1], feeds[argc-1]
+	}
+	if ln.flFromFile != "" {
+		fromFile, err := readFeedList(ln.flFromFile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ln:", err)
+			if os.IsNotExist(err) {
+				exit(EX_NOINPUT)
+			}
+			exit(EX_IOERR)
+		}
+		feeds = append(feeds, fromFile...)
+	}
+	if ln.flTitle != "" && len(feeds) > 1 {
+		fmt.Fprintln(os.Stderr, "ln: --title takes only one feed")
+		exit(EX_USAGE)
+	}
+	var item *ttrss.FeedTreeItem
+	var err error
+	switch {
+	case ln.flDryRun:
+		item, err = ln.planCategory(catpath)
+	case ln.flParents:
+		item, err = ln.makeCategory(catpath)
+	default:
+		item, err = ResolveCatPath(catpath)
+	}
+	if err != nil {
+		printCandidates(err)
+		log.Fatalln(err)
+	}
+
+	if item.Type != ttrss.Category {
+		log.Fatalln("error: not a category:", catpath)
+	}
+	if item.IsVirtual() {
+		log.Fatalln("error: can't subscribe within", catpath)
+	}
+
+	code := EX_SUCCESS
+	if ln.flDryRun {
+		for _, feed := range feeds {
+			if !ln.check(feed, catpath) {
+				code = EX_DATAERR
+			}
+		}
+		exit(code)
+	}
+
+	// Only fetching and subscribing go on at once: choosing among the
+	// feeds a page offers, and reporting, go in order.
+	batch := len(feeds) > 1 || ln.flFromFile != ""
+	subscribed, failed := 0, 0
+	discoveries := make([]discovery, len(feeds))
+	var chosen []string
+	inParallel(len(feeds), ln.flJobs, func(i int) {
+		discoveries[i] = discover(feeds[i], ln.flFeedUser, ln.flFeedPass)
+	}, func(i int) {
+		feed, found := ln.choose(feeds[i], discoveries[i])
+		if !found {
+			code = EX_DATAERR
+			failed++
+			return
+		}
+		chosen = append(chosen, feed)
+	})
+
+	results := make([]subscription, len(chosen))
+	inParallel(len(chosen), ln.flJobs, func(i int) {
+		results[i].tries, results[i].err = subscribeRetryingFetch(chosen[i],
+			item.ID, ln.flFeedUser, ln.flFeedPass, ln.flRetryFetch)
+	}, func(i int) {
+		added, ok := ln.record(chosen[i], catpath, item, results[i])
+		if !ok {
+			code = EX_DATAERR
+			failed++
+		}
+		if added {
+			subscribed++
+		}
+		if added && batch && !ln.flOnlyErrors {
+			fmt.Printf("subscribed to %s\n", display(chosen[i]))
+		}
+	})
+	if batch {
+		infof("ln: subscribed to %d %s, %d failed", subscribed,
+			plural(subscribed, "feed", "feeds"), failed)
+	}
+	exit(code)
+}
+
+// replace subscribes to feedURL in place of the feed at catpath, filing it
+// in the same category, under the same title, and unsubscribes from the old
+// one, and returns the exit code for how that went. The old feed's articles
+// go with it.
+// The old feed goes only once the new one is subscribed to, but even if it
+// couldn't be given the old title.
+func (ln *Ln) replace(feedURL, catpath string) int {
+	old, err := ResolveCatPath(catpath)
+	var cat *ttrss.FeedTreeItem
+	parentPath, _ := splitCatPath(catpath)
+	if err == nil {
+		cat, err = ResolveCatPath(parentPath)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ln:", err)
+		printCandidates(err)
+		var pathErr *PathError
+		if errors.As(err, &pathErr) {
+			return EX_NOINPUT
+		}
+		return EX_DATAERR
+	}
+	if old.Type != ttrss.Feed || old.IsVirtual() {
+		fmt.Fprintf(os.Stderr, "ln: not a feed: %q\n", catpath)
+		return EX_DATAERR
+	}
+	if ln.flTitle == "" {
+		ln.flTitle = old.Name
+	}
+
+	if ln.flDryRun {
+		if !ln.check(feedURL, parentPath) {
+			return EX_DATAERR
+		}
+		fmt.Printf("would remove %s\n", display(catpath))
+		return EX_SUCCESS
+	}
+
+	feed, ok := ln.choose(feedURL,
+		discover(feedURL, ln.flFeedUser, ln.flFeedPass))
+	if !ok {
+		return EX_DATAERR
+	}
+	var sub subscription
+	sub.tries, sub.err = subscribeRetryingFetch(feed, cat.ID,
+		ln.flFeedUser, ln.flFeedPass, ln.flRetryFetch)
+	added, ok := ln.record(feed, parentPath, cat, sub)
+	if !added {
+		fmt.Fprintf(os.Stderr, "ln: left %s in place\n", display(catpath))
+		return EX_DATAERR
+	}
+
+	if err := unsubscribe("ln", catpath, old); err != nil {
+		fmt.Fprintf(os.Stderr, "ln: %s: %v\n", catpath, err)
+		return EX_UNAVAILABLE
+	}
+	fmt.Printf("replaced %s with %s\n", display(catpath), display(feed))
+	if !ok {
+		return EX_DATAERR
+	}
+	return EX_SUCCESS
+}
+
+// readFeedList reads the feed URLs listed in the file at filePath, or on
+// stdin if it's "-": one per line, ignoring blank lines and lines starting
+// with #.
+func readFeedList(filePath string) (feeds []string, err error) {
+	file := os.Stdin
+	if filePath != "-" {
+		file, err = os.Open(filePath)
+		if err != nil {
+			return
+		}
+		defer file.Close()
+	}
+
+	lines := bufio.NewScanner(file)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			feeds = append(feeds, line)
+		}
+	}
+	if err = lines.Err(); err != nil {
+		err = fmt.Errorf("reading %s: %v", filePath, err)
+	}
+	return
+}
+
+// makeCategory returns the category at catpath, creating it and any missing
+// above it first, and reporting each one created on stderr. A feed of the
+// same name in the way is no reason to stop: the user asked for the category
+// to file a feed in, not for a tidy tree.
+func (ln *Ln) makeCategory(catpath string) (*ttrss.FeedTreeItem, error) {
+	id, err := makeCategory("ln", catpath, true, true,
+		func(id int, created string) {
+			infof("ln: created category %s", display(created))
+		})
+	if err != nil {
+		return nil, err
+	}
+	return &ttrss.FeedTreeItem{ID: id, Type: ttrss.Category}, nil
+}
+
+// planCategory is makeCategory for a dry run: it returns the category at
+// catpath, or with -p, if there's none, a stand-in for it, saying that it
+// would be created.
+func (ln *Ln) planCategory(catpath string) (*ttrss.FeedTreeItem, error) {
+	item, err := ResolveCatPath(asCategoryPath(catpath))
+	var pathErr *PathError
+	if ln.flParents && errors.As(err, &pathErr) {
+		fmt.Printf("would create category %s\n", display(catpath))
+		return &ttrss.FeedTreeItem{Type: ttrss.Category}, nil
+	}
+	return item, err
+}
+
+// isFeedURL reports whether arg looks like a feed URL rather than a catpath.
+func isFeedURL(arg string) bool {
+	return strings.Contains(arg, "://")
+}
+
+// discovery is what discover found at a URL.
+type discovery struct {
+	feeds  []DiscoveredFeed
+	isPage bool
+	err    error
+}
+
+// discover fetches feedURL, as feedUser if that's set, to see whether it's a
+// web page offering feeds rather than a feed itself.
+func discover(feedURL, feedUser, feedPass string) (found discovery) {
+	found.feeds, found.isPage, found.err = discoverFeeds(feedURL, feedUser,
+		feedPass)
+	return
+}
+
+// choose returns the feed to subscribe to for feedURL, given what
+// discover found there: feedURL itself, unless it's a web page, and
+// then the feed that it offers. ok reports whether there was one to choose;
+// why not goes to stderr.
+// If the page couldn't be fetched from here, the server may yet manage it.
+func (ln *Ln) choose(feedURL string, found discovery) (feed string,
+	ok bool) {
+	if found.err != nil {
+		verbosef("ln: %s: leaving it to the server: %v", display(feedURL),
+			found.err)
+		return feedURL, true
+	}
+	if !found.isPage {
+		return feedURL, true
+	}
+	return ln.pick(feedURL, found.feeds)
+}
+
+// pick returns the feed to subscribe to out of feeds, those offered by the
+// web page at pageURL. ok reports whether there was one to pick; why not
+// goes to stderr.
+func (ln *Ln) pick(pageURL string, feeds []DiscoveredFeed) (feed string,
+	ok bool) {
+	switch {
+	case len(feeds) == 0:
+		fmt.Fprintf(os.Stderr, "ln: %s: a web page offering no feeds\n",
+			display(pageURL))
+		return "", false
+	case ln.flPick > len(feeds):
+		fmt.Fprintf(os.Stderr, "ln: %s: no feed %d: the page offers %d\n",
+			display(pageURL), ln.flPick, len(feeds))
+		return "", false
+	case ln.flPick > 0:
+		feed = feeds[ln.flPick-1].URL
+	case len(feeds) == 1:
+		feed = feeds[0].URL
+	default:
+		choice, ok := pickFeed(os.Stdin, os.Stderr, pageURL, feeds,
+			isTerminal(os.Stdin) && ln.flFromFile != "-")
+		if !ok {
+			return "", false
+		}
+		feed = choice.URL
+	}
+	infof("ln: %s: found feed %s", display(pageURL), display(feed))
+	return feed, true
+}
+
+// check fetches feedURL, or the feed it offers if it's a web page, and says
+// what subscribing to it in catpath would get, or on stderr, why it can't be
+// subscribed to. ok reports which.
+// Unlike a real subscription, nothing is left to the server: a feed that
+// can't be fetched from here fails.
+func (ln *Ln) check(feedURL, catpath string) (ok bool) {
+	feed := feedURL
+	body, mediaType, found, err := fetchFeed(feed, ln.flFeedUser,
+		ln.flFeedPass)
+	if err == nil && isPageType(mediaType) {
+		feed, ok = ln.pick(feedURL, feedLinks(string(body), found))
+		if !ok {
+			return
+		}
+		body, mediaType, _, err = fetchFeed(feed, ln.flFeedUser,
+			ln.flFeedPass)
+	}
+	var summary FeedSummary
+	if err == nil {
+		summary, err = summarizeFeed(body, mediaType)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ln: %s: %v\n", display(feed), err)
+		return false
+	}
+
+	latest := "no dates"
+	if !summary.Latest.IsZero() {
+		latest = "latest " + summary.Latest.Local().Format("2006-01-02 15:04")
+	}
+	title := summary.Title
+	if ln.flTitle != "" {
+		title = ln.flTitle
+	}
+	if title == "" {
+		title = "(untitled)"
+	}
+	fmt.Printf("would subscribe to %s in %s: %s (%s, %d %s, %s)\n",
+		display(feed), display(catpath), display(title), summary.Format,
+		summary.Items, plural(summary.Items, "item", "items"), latest)
+	return true
+}
+
+// pickFeed lists the feeds offered by the page at pageURL on w, then, if ask
+// is set, asks which to take, reading the answer from r.
+// ok is false if there was no answer, or nobody to ask.
+func pickFeed(r io.Reader, w io.Writer, pageURL string,
+	feeds []DiscoveredFeed, ask bool) (feed DiscoveredFeed, ok bool) {
+	fmt.Fprintf(w, "ln: %s offers %d feeds:\n", display(pageURL), len(feeds))
+	for i, feed := range feeds {
+		title := feed.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		fmt.Fprintf(w, "  %d) %s %s\n", i+1, display(title),
+			display(feed.URL))
+	}
+	if !ask {
+		fmt.Fprintln(w, "ln: choose one with --pick N")
+		return
+	}
+
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprintf(w, "which one? [1-%d] ", len(feeds))
+		if !scanner.Scan() {
+			fmt.Fprintln(w)
+			return
+		}
+		choice, err := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if err == nil && 1 <= choice && choice <= len(feeds) {
+			return feeds[choice-1], true
+		}
+	}
+}
+
+// subscription is how subscribing to a feed went: tries reports how many
+// attempts it took, and err, as for Subscribe, how the last one went.
+type subscription struct {
+	tries int
+	err   error
+}
+
+// record reports how subscribing to feed in cat, found at catpath, went, and
+// logs the change, renaming the feed if --title asks.
+// added reports whether it was newly subscribed to, and ok whether all went
+// well; what didn't goes to stderr.
+func (ln *Ln) record(feed, catpath string, cat *ttrss.FeedTreeItem,
+	sub subscription) (added, ok bool) {
+	tries, err := sub.tries, sub.err
+	if tries > 1 {
+		infof("ln: %s: needed %d attempts to fetch feed", display(feed),
+			tries)
+	}
+
+	result := "ok"
+	s, isStatus := err.(*ttrss.SubscribeError)
+	added = isStatus && s.Status == ttrss.SUB_ADDED
+	already := isStatus && s.Status == ttrss.SUB_ALREADY_ADDED
+	if isStatus && !added {
+		result = s.Error()
+		message := s.Message
+		if already {
+			// There's no underlying error to tell of.
+			message = s.Status.String()
+		}
+		if !already || !ln.flIdempotent && !ln.flOnlyErrors {
+			fmt.Fprintf(os.Stderr, "ln: %s: %s\n", display(feed), message)
+		}
+	} else if !isStatus && err != nil {
+		fmt.Fprintf(os.Stderr, "ln: %s: %v\n", display(feed), err)
+		result = err.Error()
+	}
+	logChange(ChangelogEntry{
+		Op: "ln", Path: catpath, ID: cat.ID, URL: feed, Result: result})
+
+	if !added {
+		return false, already
+	}
+	stats.affected++
+	if ln.flTitle != "" {
+		return true, ln.retitle(feed, catpath, cat, s.FeedID)
+	}
+	return true, true
+}
+
+// retitle renames the feed at feedURL, just subscribed to in cat, found at
+// catpath, to the title given by --title, and logs the change. feedID is
+// the new feed's ID, or zero if the server didn't say, and then it's looked
+// up by URL instead.
+// It reports whether the feed was renamed; why not goes to stderr.
+func (ln *Ln) retitle(feedURL, catpath string, cat *ttrss.FeedTreeItem,
+	feedID int) bool {
+	if feedID == 0 {
+		feeds, err := tt.GetFeeds(cat.ID, false, false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ln: %s: subscribed, but can't rename "+
+				"it: %v\n", display(feedURL), err)
+			return false
+		}
+		for _, feed := range feeds {
+			// The newest feed at that URL is the one just subscribed to.
+			if feed.FeedURL == feedURL && feed.ID > feedID {
+				feedID = feed.ID
+			}
+		}
+		if feedID == 0 {
+			fmt.Fprintf(os.Stderr, "ln: %s: subscribed, but can't find the "+
+				"new feed to rename it\n", display(feedURL))
+			return false
+		}
+	}
+
+	err := tt.RenameFeed(feedID, ln.flTitle)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	to := asCategoryPath(catpath) + ttrss.EscapePathComponent(ln.flTitle)
+	logChange(ChangelogEntry{Op: "ln", Path: catpath, ID: feedID, To: to,
+		URL: feedURL, Result: result})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ln: %s: subscribed, but can't rename it: "+
+			"%v\n", display(feedURL), err)
+		return false
+	}
+	return true
+}
+
+// Pacing for subscribeRetryingFetch. These are variables only so that tests
+// needn't wait.
+var (
+	fetchRetryDelay  = 2 * time.Second
+	fetchRetryBudget = time.Minute
+)
+
+// subscribeRetryingFetch subscribes to feedURL, fetched as feedUser if that's
+// set, retrying up to retries times if the server reports that it could not
+// fetch the feed.
+// Other failures are not retried: they won't go away by themselves.
+// Retries back off exponentially, and stop once fetchRetryBudget is spent.
+// tries reports how many subscription attempts were made.
+func subscribeRetryingFetch(feedURL string, categoryID int,
+	feedUser, feedPass string, retries int) (tries int, err error) {
+	deadline := time.Now().Add(fetchRetryBudget)
+	delay := fetchRetryDelay
+	for {
+		_, err = tt.Subscribe(feedURL, categoryID, feedUser, feedPass)
+		tries++
+
+		s, ok := err.(*ttrss.SubscribeError)
+		if !ok || s.Status != ttrss.SUB_GET_FAILED || tries > retries {
+			return
+		}
+		if time.Now().Add(delay).After(deadline) {
+			return
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
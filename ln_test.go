@@ -0,0 +1,168 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"ttrss"
+)
+
+// subscribeOp answers subscribeToFeed with each of statuses in turn, and
+// then with the last of them.
+func subscribeOp(statuses ...ttrss.SubscribeStatus) stubOp {
+	calls := 0
+	return func(map[string]interface{}) interface{} {
+		status := statuses[len(statuses)-1]
+		if calls < len(statuses) {
+			status = statuses[calls]
+		}
+		calls++
+		return map[string]interface{}{"status": map[string]interface{}{
+			"code": int(status), "feed_id": 20}}
+	}
+}
+
+func TestSubscribeRetryingFetch(t *testing.T) {
+	savedDelay, savedBudget := fetchRetryDelay, fetchRetryBudget
+	defer func() {
+		fetchRetryDelay, fetchRetryBudget = savedDelay, savedBudget
+	}()
+	fetchRetryDelay = time.Millisecond
+
+	failed, added := ttrss.SUB_GET_FAILED, ttrss.SUB_ADDED
+	tests := []struct {
+		name      string
+		statuses  []ttrss.SubscribeStatus
+		retries   int
+		budget    time.Duration
+		wantTries int
+		want      ttrss.SubscribeStatus
+	}{
+		{"no retries", []ttrss.SubscribeStatus{failed, added}, 0,
+			time.Minute, 1, failed},
+		{"failed then added", []ttrss.SubscribeStatus{failed, added}, 2,
+			time.Minute, 2, added},
+		{"added at once", []ttrss.SubscribeStatus{added}, 2,
+			time.Minute, 1, added},
+		{"retries run out", []ttrss.SubscribeStatus{failed}, 2,
+			time.Minute, 3, failed},
+		{"only fetch failures retried",
+			[]ttrss.SubscribeStatus{ttrss.SUB_INVALID_URL, added}, 2,
+			time.Minute, 1, ttrss.SUB_INVALID_URL},
+		{"budget runs out", []ttrss.SubscribeStatus{failed, added}, 2,
+			time.Microsecond, 1, failed},
+	}
+	for _, test := range tests {
+		useStub(t, newStubServer(t, map[string]stubOp{
+			"subscribeToFeed": subscribeOp(test.statuses...)}))
+		fetchRetryBudget = test.budget
+
+		tries, err := subscribeRetryingFetch("http://example.com/feed", 0,
+			"", "", test.retries)
+		s, ok := err.(*ttrss.SubscribeError)
+		if !ok || tries != test.wantTries || s.Status != test.want {
+			t.Errorf("%s: got %d tries, %v; want %d tries, %v", test.name,
+				tries, err, test.wantTries, test.want)
+		}
+	}
+}
+
+func TestPasswordFromCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+		wantErr bool
+	}{
+		{"printf s3cret", "s3cret", false},
+		{`printf 's3cret\n'`, "s3cret", false},
+		{`printf ' s3 cret \n\n'`, " s3 cret \n", false},
+		{"printf ''", "", true},
+		{"exit 3", "", true},
+	}
+	for _, test := range tests {
+		got, err := passwordFromCommand(test.command)
+		if got != test.want || (err != nil) != test.wantErr {
+			t.Errorf("passwordFromCommand(%q) = %q, %v; want %q, error: %v",
+				test.command, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestLnFeedPassCommand(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree":     treeOp(),
+		"subscribeToFeed": subscribeOp(ttrss.SUB_ADDED),
+	})
+	stub.feeds["/feed.xml"] = stubRSS
+
+	_, stderr, code := runTool(t, stub, "", "ln", "--feed-user", "me",
+		"--feed-pass-command", `printf 's3cret\n'`, stub.URL+"/feed.xml")
+	if code != EX_SUCCESS {
+		t.Fatalf("ln --feed-pass-command: got exit %d, stderr %q", code,
+			stderr)
+	}
+	calls := stub.called("subscribeToFeed")
+	if len(calls) != 1 || calls[0].Req["login"] != "me" ||
+		calls[0].Req["password"] != "s3cret" {
+		t.Errorf("ln --feed-pass-command: got subscribeToFeed calls %+v, "+
+			"want one as me with password s3cret", calls)
+	}
+}
+
+func TestLnOnlyErrors(t *testing.T) {
+	for _, onlyErrors := range []bool{false, true} {
+		stub := newStubServer(t, map[string]stubOp{
+			"getFeedTree": treeOp(),
+			"subscribeToFeed": subscribeOp(ttrss.SUB_ADDED,
+				ttrss.SUB_INVALID_URL, ttrss.SUB_ADDED),
+		})
+		for _, name := range []string{"/a.xml", "/b.xml", "/c.xml"} {
+			stub.feeds[name] = stubRSS
+		}
+		list := stub.URL + "/a.xml\n" + stub.URL + "/b.xml\n" +
+			stub.URL + "/c.xml\n"
+
+		args := []string{"ln", "-f", "-"}
+		if onlyErrors {
+			args = []string{"ln", "--only-errors", "-f", "-"}
+		}
+		stdout, stderr, code := runTool(t, stub, list, args...)
+		wantStdout := "subscribed to " + stub.URL + "/a.xml\n" +
+			"subscribed to " + stub.URL + "/c.xml\n"
+		if onlyErrors {
+			wantStdout = ""
+		}
+		lines := strings.Split(strings.TrimSuffix(stderr, "\n"), "\n")
+		if code != EX_DATAERR || stdout != wantStdout || len(lines) != 2 ||
+			!strings.HasPrefix(lines[0], "ln: "+stub.URL+"/b.xml: ") ||
+			lines[1] != "ln: subscribed to 2 feeds, 1 failed" {
+			t.Errorf("%q: got exit %d, stdout %q, stderr %q; want exit "+
+				"%d, stdout %q, and b.xml's failure and the count on "+
+				"stderr", args, code, stdout, stderr, EX_DATAERR,
+				wantStdout)
+		}
+	}
+}
+
+func TestLnAlreadySubscribed(t *testing.T) {
+	for _, idempotent := range []bool{false, true} {
+		stub := newStubServer(t, map[string]stubOp{
+			"getFeedTree":     treeOp(),
+			"subscribeToFeed": subscribeOp(ttrss.SUB_ALREADY_ADDED),
+		})
+		stub.feeds["/feed.xml"] = stubRSS
+
+		args := []string{"ln", stub.URL + "/feed.xml"}
+		if idempotent {
+			args = []string{"ln", "--idempotent", stub.URL + "/feed.xml"}
+		}
+		_, stderr, code := runTool(t, stub, "", args...)
+		if code != EX_SUCCESS || (stderr == "") != idempotent {
+			t.Errorf("%q: got exit %d, stderr %q; want exit 0, and "+
+				"a mention on stderr only without --idempotent", args, code,
+				stderr)
+		}
+	}
+}
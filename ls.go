@@ -0,0 +1,805 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"ttrss"
+)
+
+type Ls struct {
+	flHelp      bool
+	flRecurse   bool
+	flOneColumn bool
+	flColumns   bool
+	flArticles  bool
+	flAll       bool
+	flClassify  bool
+	flLong      bool
+	flDirectory bool
+	flIDs       bool
+	flMaxDepth  int
+	flLimit     int
+	flFormat    string
+	flPorcelain porcelainValue
+	flByTime    bool
+	flBySize    bool
+	flReverse   bool
+	flUnsorted  bool
+	flags       flag.FlagSet
+
+	// showVirtual is set when the server's own categories and feeds are
+	// listed.
+	showVirtual bool
+
+	// markVirtual is set when -a mixes the server's own categories and
+	// feeds in with the user's.
+	markVirtual bool
+
+	// format is the parsed --format template, if any.
+	format *template.Template
+
+	// listed is set once anything has been listed, so that each group of
+	// entries after that is set off by a blank line.
+	listed bool
+
+	// shown counts the entries listed within categories, for --limit, and
+	// more those that --limit left out.
+	shown, more int
+
+	// counters and feedByID are fetched by details.
+	counters ttrss.Counters
+	feedByID map[int]ttrss.FeedInfo
+}
+
+func (ls *Ls) Init() {
+	ls.flags.Init("ls", flag.PanicOnError)
+
+	ls.flags.BoolVar(&ls.flHelp, "h", false, "help")
+	ls.flags.BoolVar(&ls.flHelp, "help", false, "help")
+
+	recurseUsage := "recurse into categories"
+	ls.flags.BoolVar(&ls.flRecurse, "R", false, recurseUsage)
+	ls.flags.BoolVar(&ls.flRecurse, "Recurse", false, recurseUsage)
+	ls.flags.IntVar(&ls.flMaxDepth, "maxdepth", 0,
+		"with -R, stop `N` levels down (0 means no limit)")
+	ls.flags.IntVar(&ls.flLimit, "limit", 0,
+		"stop after listing `N` entries, noting how many more there were "+
+			"(0 means no limit)")
+
+	ls.flags.BoolVar(&ls.flOneColumn, "1", false,
+		"list one entry per line (overrides -C)")
+	ls.flags.BoolVar(&ls.flColumns, "C", false,
+		"list entries in columns, even when not writing to a terminal")
+
+	ls.flags.BoolVar(&ls.flAll, "a", false,
+		"include the server's own categories and feeds, like Special, "+
+			"marked with @")
+
+	ls.flags.BoolVar(&ls.flClassify, "F", false,
+		"append / to category names")
+
+	ls.flags.BoolVar(&ls.flByTime, "t", false,
+		"sort by last update, most recent first")
+	ls.flags.BoolVar(&ls.flBySize, "S", false,
+		"sort by unread count, most first")
+	ls.flags.BoolVar(&ls.flReverse, "r", false, "reverse the sort order")
+	ls.flags.BoolVar(&ls.flUnsorted, "U", false,
+		"don't sort; list entries in the server's order")
+
+	ls.flags.BoolVar(&ls.flLong, "l", false,
+		"list type, ID, unread count, last update, name, and URL")
+
+	ls.flags.BoolVar(&ls.flIDs, "i", false,
+		"print each entry's ID before its name")
+	ls.flags.BoolVar(&ls.flDirectory, "d", false,
+		"list categories themselves, not what's in them")
+
+	ls.flags.BoolVar(&ls.flArticles, "articles", false,
+		"treat feeds as directories of articles")
+
+	ls.flags.StringVar(&ls.flFormat, "format", "",
+		"print each entry using the Go text/template `TEMPLATE`")
+	ls.flags.Var(&ls.flPorcelain, "porcelain", porcelainUsage)
+}
+
+func (ls *Ls) Flags() *flag.FlagSet {
+	return &ls.flags
+}
+
+func (ls *Ls) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "ls [-1aCdFilRrStU] [--maxdepth N] [--limit N] "+
+		"[--articles] "+
+		"[--format TEMPLATE|--porcelain] [catpath...] "+
+		"-- list categories and feeds")
+}
+
+// Run lists what's in each category named, under a "catpath:" heading if
+// there's more than one, after listing any feeds named.
+// With -d, categories are listed as feeds are, by name.
+// A catpath with wildcards lists everything it matches.
+// It carries on past catpaths it can't resolve, and exits with the code for
+// the last one: EX_NOINPUT if nothing is at the path, and EX_DATAERR if it's
+// ambiguous.
+func (ls *Ls) Run(args []string) {
+	_ = ls.flags.Parse(args)
+	if ls.flHelp {
+		flagSetPrintUsage(ls.flags, os.Stdout, "ls")
+		return
+	}
+
+	if ls.flPorcelain != "" && (ls.flLong || ls.flFormat != "" ||
+		ls.flArticles) || ls.flLimit < 0 {
+		flagSetPrintUsage(ls.flags, os.Stderr, "ls")
+		exit(EX_USAGE)
+	}
+	if ls.flFormat != "" {
+		samples := []interface{}{EntryView{}}
+		if ls.flArticles {
+			// Feeds list their articles, but categories still list feeds.
+			samples = append(samples, HeadlineView{})
+		}
+		if ls.flLong {
+			flagSetPrintUsage(ls.flags, os.Stderr, "ls")
+			exit(EX_USAGE)
+		}
+		format, err := parseFormat(ls.flFormat, samples...)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ls: bad --format:", err)
+			exit(EX_USAGE)
+		}
+		ls.format = format
+	}
+
+	catpaths := ls.flags.Args()
+	if len(catpaths) == 0 {
+		catpaths = []string{"/"}
+	}
+
+	code := EX_SUCCESS
+	var articles []ttrss.Headline
+	var feeds, cats []lsEntry
+	operands := 0
+	for _, catpath := range catpaths {
+		var item *ttrss.FeedTreeItem
+		var article *ttrss.Headline
+		var err error
+		if ls.flArticles {
+			item, article, err = ResolveArticlePath(catpath)
+		} else {
+			item, err = ResolveCatPath(catpath)
+		}
+		// A catpath is a pattern only if it names nothing as it stands, so
+		// that "/News/[Blog] Foo" can name a feed called that.
+		var pathErr *PathError
+		if errors.As(err, &pathErr) && hasGlob(catpath) {
+			matches, err := ls.glob(catpath)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "ls:", err)
+				exit(EX_UNAVAILABLE)
+			}
+			if len(matches) == 0 {
+				fmt.Fprintf(os.Stderr, "ls: no match: %q\n", catpath)
+				code = EX_NOINPUT
+			}
+			for _, entry := range matches {
+				if ls.flDirectory || entry.item.Type == ttrss.Feed {
+					feeds = append(feeds, entry)
+				} else {
+					cats = append(cats, entry)
+				}
+			}
+			operands += len(matches)
+			continue
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "ls:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		// With --articles, a feed is listed like a category; with -d,
+		// a category is listed like a feed.
+		entry := lsEntry{catpath, item}
+		operands++
+		switch {
+		case article != nil:
+			articles = append(articles, *article)
+		case ls.flDirectory:
+			feeds = append(feeds, entry)
+		case item.Type == ttrss.Category || ls.flArticles:
+			cats = append(cats, entry)
+		default:
+			feeds = append(feeds, entry)
+		}
+	}
+
+	for _, article := range articles {
+		ls.printArticle(article)
+		ls.listed = true
+	}
+	if len(feeds) > 0 {
+		ls.listEntries(feeds, "")
+	}
+
+	ls.sortEntries(cats)
+	headed := operands > 1 || ls.flRecurse
+	for _, cat := range cats {
+		if cat.item.Type == ttrss.Feed {
+			ls.heading(cat.name, headed)
+			if err := ls.listArticles(cat.item); err != nil {
+				fmt.Fprintf(os.Stderr, "ls: %s: %v\n", cat.name, err)
+				code = EX_UNAVAILABLE
+			}
+			continue
+		}
+
+		if operands == 1 && cat.item.IsRoot() &&
+			len(ls.entries(cat.item)) == 0 {
+			// Say so, rather than leaving a new user staring at nothing.
+			// This goes to stderr so that pipelines still see an empty
+			// listing.
+			infof("(no categories)")
+			continue
+		}
+		ls.listRecursively(cat.item, cat.name, headed, 1)
+	}
+	if ls.more > 0 {
+		infof("…(truncated, %d more)", ls.more)
+	}
+	exit(code)
+}
+
+// glob returns the entries matching pattern, as GlobCatPath finds them.
+// As the shell's wildcards pass over dotfiles, these pass over the server's
+// own categories, like Special, and all within them, unless -a is given;
+// naming one outright is fine.
+func (ls *Ls) glob(pattern string) (entries []lsEntry, err error) {
+	matches, err := GlobCatPath(pattern)
+	if err != nil {
+		return
+	}
+
+	// The server's own categories are all at the top.
+	var hidden []string
+	parts := PathComponents(pattern)
+	if !ls.flAll && len(parts) > 0 && hasGlob(parts[0]) {
+		tree, err := tt.GetFeedTree(true)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range tree.Items {
+			if item.IsVirtual() {
+				hidden = append(hidden,
+					"/"+ttrss.EscapePathComponent(item.Name))
+			}
+		}
+	}
+
+	for _, match := range matches {
+		if !isWithin(match.Path, hidden) {
+			entries = append(entries, lsEntry{match.Path, match.Item})
+		}
+	}
+	return
+}
+
+// isWithin reports whether catpath is any of catpaths, or lies within one.
+func isWithin(catpath string, catpaths []string) bool {
+	for _, within := range catpaths {
+		if catpath == within || strings.HasPrefix(catpath, within+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// lsEntry is something to list, under the name to list it by.
+type lsEntry struct {
+	name string
+	item *ttrss.FeedTreeItem
+}
+
+// entries returns the items in cat that should be listed.
+func (ls *Ls) entries(cat *ttrss.FeedTreeItem) []lsEntry {
+	entries := make([]lsEntry, 0, len(cat.Items))
+	for i := range cat.Items {
+		item := &cat.Items[i]
+		if item.IsVirtual() && !ls.showVirtual {
+			continue
+		}
+		entries = append(entries, lsEntry{item.Name, item})
+	}
+	return entries
+}
+
+// heading starts a new group of entries in the listing, titled catpath if
+// headed. With --format or --porcelain, there are no groups, only entries.
+func (ls *Ls) heading(catpath string, headed bool) {
+	if ls.format != nil || ls.flPorcelain != "" {
+		return
+	}
+	if ls.listed {
+		fmt.Println()
+	}
+	if headed {
+		fmt.Printf("%s:\n", display(catpath))
+	}
+	ls.listed = true
+}
+
+// listEntries lists entries, found in the category at catpath, one per line
+// or in columns. If catpath is "", their names are their catpaths.
+// The long format shows IDs anyway, so -i makes no difference to it.
+func (ls *Ls) listEntries(entries []lsEntry, catpath string) {
+	ls.listed = true
+	ls.sortEntries(entries)
+	if ls.format != nil {
+		ls.listFormatted(entries, catpath)
+		return
+	}
+	if ls.flPorcelain != "" {
+		ls.listPorcelain(entries, catpath)
+		return
+	}
+	if ls.flLong {
+		ls.listLong(entries)
+		return
+	}
+
+	// As ls -i lines up inode numbers, line up IDs, which can be negative.
+	idWidth := 0
+	if ls.flIDs {
+		for _, entry := range entries {
+			if w := len(fmt.Sprint(entry.item.ID)); w > idWidth {
+				idWidth = w
+			}
+		}
+	}
+
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = ls.entryName(entry)
+		if ls.flIDs {
+			names[i] = fmt.Sprintf("%*d %s", idWidth, entry.item.ID,
+				names[i])
+		}
+	}
+
+	columns := ls.flColumns || isTerminal(os.Stdout)
+	if ls.flOneColumn || !columns {
+		for _, name := range names {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	for _, line := range packColumns(names, terminalWidth(os.Stdout)) {
+		fmt.Println(line)
+	}
+}
+
+// listRecursively lists cat, found at catpath depth levels below where the
+// listing started, under a heading if headed. With -R, it goes on to list
+// each category in it in turn, always headed, as ls -R does, stopping
+// --maxdepth levels down, if that's set.
+// Once --limit entries have been listed, the rest are only counted.
+func (ls *Ls) listRecursively(cat *ttrss.FeedTreeItem, catpath string,
+	headed bool, depth int) {
+	// Inside a virtual category, everything is virtual; no sense hiding it.
+	showVirtual, markVirtual := ls.showVirtual, ls.markVirtual
+	if depth == 1 {
+		ls.showVirtual = ls.flAll || cat.IsVirtual()
+		ls.markVirtual = ls.flAll && !cat.IsVirtual()
+	}
+	defer func() {
+		ls.showVirtual, ls.markVirtual = showVirtual, markVirtual
+	}()
+
+	entries := ls.entries(cat)
+	switch {
+	case ls.flLimit > 0 && ls.shown >= ls.flLimit:
+		ls.more += len(entries)
+	case ls.flLimit > 0 && ls.shown+len(entries) > ls.flLimit:
+		ls.heading(catpath, headed)
+		// Sort first, so that it's the first entries that are listed.
+		ls.sortEntries(entries)
+		shown := entries[:ls.flLimit-ls.shown]
+		ls.listEntries(shown, catpath)
+		ls.shown += len(shown)
+		ls.more += len(entries) - len(shown)
+	default:
+		ls.heading(catpath, headed)
+		ls.listEntries(entries, catpath)
+		ls.shown += len(entries)
+	}
+
+	if !ls.flRecurse || ls.flMaxDepth > 0 && depth >= ls.flMaxDepth {
+		return
+	}
+	for _, entry := range entries {
+		if entry.item.Type != ttrss.Category {
+			continue
+		}
+		itemPath := strings.TrimSuffix(catpath, "/") + "/" +
+			ttrss.EscapePathComponent(entry.name)
+		ls.listRecursively(entry.item, itemPath, true, depth+1)
+	}
+}
+
+// entryName returns the name of entry as listed.
+// The server's own categories and feeds are views of articles filed
+// elsewhere, so when they're marked, it's with an @, as ls -F marks
+// symlinks, even if they're categories.
+func (ls *Ls) entryName(entry lsEntry) string {
+	name := display(entry.name)
+	switch {
+	case ls.markVirtual && entry.item.IsVirtual():
+		name += "@"
+	case ls.flClassify && entry.item.Type == ttrss.Category &&
+		!strings.HasSuffix(name, "/"):
+		// A catpath given with -d may already end in one.
+		name += "/"
+	}
+	return name
+}
+
+// Layout of the time of last update in ls -l.
+const longTimeLayout = "2006-01-02 15:04"
+
+// listLong lists entries in the long format, one per line, in columns:
+// "d" for a category or "-" for a feed, then the ID, the unread count, the
+// time of the last update, the name, and the feed URL. A feed whose last
+// update failed ends with why.
+// What isn't known, like a category's URL, is "-".
+func (ls *Ls) listLong(entries []lsEntry) {
+	counters, feedByID := ls.details()
+
+	rows := make([][]string, len(entries))
+	for i, entry := range entries {
+		item := entry.item
+		kind, updated, url := "d", "-", "-"
+		if item.Type == ttrss.Feed {
+			kind = "-"
+		}
+		if feed, ok := feedByID[item.ID]; ok && item.Type == ttrss.Feed {
+			if feed.LastUpdated.Unix() > 0 {
+				updated = feed.LastUpdated.Format(longTimeLayout)
+			} else {
+				updated = "never"
+			}
+			url = display(feed.FeedURL)
+		}
+		// Special's feeds overlap, so adding them up means nothing.
+		unread := "-"
+		if item.Type == ttrss.Feed || !item.IsVirtual() {
+			unread = fmt.Sprint(unreadIn(item, counters))
+		}
+		rows[i] = []string{kind, fmt.Sprint(item.ID), unread, updated,
+			ls.entryName(entry), url}
+		if item.LastError != "" {
+			rows[i] = append(rows[i], "error: "+display(item.LastError))
+		}
+	}
+
+	for _, line := range alignColumns(rows, 1, 2) {
+		fmt.Println(line)
+	}
+}
+
+// EntryView is a category or feed as ls --format templates see it.
+// FeedURL and Updated are only known for feeds, and Error is only set for
+// a feed whose last update failed. Strings are made safe for the terminal,
+// as usual.
+type EntryView struct {
+	ID      int
+	Name    string
+	Path    string
+	Type    string
+	Virtual bool
+	FeedURL string
+	Unread  int
+	Updated time.Time
+	Error   string
+}
+
+// entryView returns entry, found in the category at catpath, as EntryView
+// describes it, but with strings as they came from the server.
+// If catpath is "", entry's name is its catpath.
+func (ls *Ls) entryView(entry lsEntry, catpath string) EntryView {
+	counters, feedByID := ls.details()
+	item := entry.item
+	view := EntryView{
+		ID:      item.ID,
+		Name:    item.Name,
+		Path:    entry.name,
+		Type:    item.Type,
+		Virtual: item.IsVirtual(),
+		Error:   item.LastError,
+	}
+	if catpath != "" {
+		view.Path = strings.TrimSuffix(catpath, "/") + "/" +
+			ttrss.EscapePathComponent(entry.name)
+	}
+	if item.Type == ttrss.Feed || !item.IsVirtual() {
+		view.Unread = unreadIn(item, counters)
+	}
+	if feed, ok := feedByID[item.ID]; ok && item.Type == ttrss.Feed {
+		view.FeedURL = feed.FeedURL
+		view.Updated = feed.LastUpdated
+	}
+	return view
+}
+
+// listFormatted lists entries, found in the category at catpath, as
+// --format says, one after another.
+func (ls *Ls) listFormatted(entries []lsEntry, catpath string) {
+	for _, entry := range entries {
+		view := ls.entryView(entry, catpath)
+		view.Name = display(view.Name)
+		view.Path = display(view.Path)
+		view.FeedURL = display(view.FeedURL)
+		view.Error = display(view.Error)
+		if err := printFormatted(os.Stdout, ls.format, view); err != nil {
+			log.Fatalf("unable to print %q: %v", view.Path, err)
+		}
+	}
+}
+
+// listPorcelain lists entries, found in the category at catpath, in the
+// porcelain format, one per line:
+//
+//	type id virtual unread updated catpath url error
+//
+// The type is "category" or "feed", virtual is 1 for the server's own
+// categories and feeds and otherwise 0, and updated is a Unix time, or 0 if
+// never. The unread count is empty for Special, and what isn't known, like
+// a category's URL, is empty too.
+func (ls *Ls) listPorcelain(entries []lsEntry, catpath string) {
+	for _, entry := range entries {
+		view := ls.entryView(entry, catpath)
+		virtual, unread, updated := "0", fmt.Sprint(view.Unread), "0"
+		if view.Virtual {
+			virtual = "1"
+			if view.Type == ttrss.Category {
+				unread = ""
+			}
+		}
+		if view.Updated.Unix() > 0 {
+			updated = fmt.Sprint(view.Updated.Unix())
+		}
+		printPorcelain(os.Stdout, view.Type, fmt.Sprint(view.ID), virtual,
+			unread, updated, view.Path, view.FeedURL, view.Error)
+	}
+}
+
+// details returns what the long format and sorting need that the tree
+// lacks: unread counts, and the feeds by ID for their URLs and update times.
+// They're fetched once, however many categories get listed.
+func (ls *Ls) details() (ttrss.Counters, map[int]ttrss.FeedInfo) {
+	if ls.feedByID != nil {
+		return ls.counters, ls.feedByID
+	}
+
+	counters, err := tt.GetCounters()
+	if err != nil {
+		log.Fatalf("unable to count unread articles: %v", err)
+	}
+	// The tree knows neither feed URLs nor update times; getFeeds does.
+	feeds, err := tt.GetFeeds(ttrss.CATEGORY_FEEDS_NOT_VIRTUAL, false, false)
+	if err != nil {
+		log.Fatalf("unable to get feeds: %v", err)
+	}
+	feedByID := make(map[int]ttrss.FeedInfo, len(feeds))
+	for _, feed := range feeds {
+		feedByID[feed.ID] = feed
+	}
+	ls.counters, ls.feedByID = counters, feedByID
+	return counters, feedByID
+}
+
+// sortEntries sorts entries by name, ignoring case, or as the flags say.
+// Ties are broken by name, and then by the server's order.
+func (ls *Ls) sortEntries(entries []lsEntry) {
+	if ls.flUnsorted {
+		return
+	}
+
+	var counters ttrss.Counters
+	var feedByID map[int]ttrss.FeedInfo
+	if ls.flByTime || ls.flBySize {
+		counters, feedByID = ls.details()
+	}
+	before := func(a, b lsEntry) bool {
+		if ls.flBySize {
+			unreadA := unreadIn(a.item, counters)
+			unreadB := unreadIn(b.item, counters)
+			if unreadA != unreadB {
+				return unreadA > unreadB
+			}
+		}
+		if ls.flByTime {
+			timeA := updatedIn(a.item, feedByID)
+			timeB := updatedIn(b.item, feedByID)
+			if !timeA.Equal(timeB) {
+				return timeA.After(timeB)
+			}
+		}
+		// Not the locale's collation, which would need x/text; see TODO.
+		return strings.ToLower(a.name) < strings.ToLower(b.name)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if ls.flReverse {
+			return before(entries[j], entries[i])
+		}
+		return before(entries[i], entries[j])
+	})
+}
+
+// updatedIn returns when item last updated: for a category, when the most
+// recently updated feed in it did. Virtual feeds never have.
+func updatedIn(item *ttrss.FeedTreeItem,
+	feedByID map[int]ttrss.FeedInfo) (updated time.Time) {
+	if item.Type == ttrss.Feed {
+		return feedByID[item.ID].LastUpdated
+	}
+	for i := range item.Items {
+		child := &item.Items[i]
+		if t := updatedIn(child, feedByID); t.After(updated) {
+			updated = t
+		}
+	}
+	return
+}
+
+// unreadIn returns the unread count of item: the sum of its feeds' counts,
+// if it's a category, as du counts them. Virtual feeds within a category
+// aren't counted.
+func unreadIn(item *ttrss.FeedTreeItem, counters ttrss.Counters) int {
+	if item.Type == ttrss.Feed {
+		return counters.Feeds[item.ID]
+	}
+	unread := 0
+	for i := range item.Items {
+		if child := &item.Items[i]; !child.IsVirtual() {
+			unread += unreadIn(child, counters)
+		}
+	}
+	return unread
+}
+
+// alignColumns pads the fields of rows into columns separated by
+// columnGutter spaces, aligning the columns numbered in right to the right
+// and the rest to the left. The last field of a row is never padded, so
+// rows may differ in length.
+func alignColumns(rows [][]string, right ...int) (lines []string) {
+	var widths []int
+	for _, row := range rows {
+		for col, field := range row {
+			if col == len(widths) {
+				widths = append(widths, 0)
+			}
+			if w := len([]rune(field)); w > widths[col] {
+				widths[col] = w
+			}
+		}
+	}
+	alignRight := make(map[int]bool, len(right))
+	for _, col := range right {
+		alignRight[col] = true
+	}
+
+	for _, row := range rows {
+		line := ""
+		for col, field := range row {
+			if col > 0 {
+				line += strings.Repeat(" ", columnGutter)
+			}
+			pad := strings.Repeat(" ", widths[col]-len([]rune(field)))
+			switch {
+			case alignRight[col]:
+				line += pad + field
+			case col < len(row)-1:
+				line += field + pad
+			default:
+				line += field
+			}
+		}
+		lines = append(lines, line)
+	}
+	return
+}
+
+// listArticles lists the articles in feed, newest first.
+// Article names don't pack into columns well, so they're always one per line.
+func (ls *Ls) listArticles(feed *ttrss.FeedTreeItem) error {
+	headlines, err := tt.GetHeadlines(headlinesRequestFor(feed))
+	if err != nil {
+		return err
+	}
+	for _, h := range headlines {
+		ls.printArticle(h)
+	}
+	return nil
+}
+
+// printArticle prints h as --format says, or else as printArticleEntry does.
+func (ls *Ls) printArticle(h ttrss.Headline) {
+	if ls.format == nil {
+		printArticleEntry(h)
+		return
+	}
+	err := printFormatted(os.Stdout, ls.format, viewHeadline(h))
+	if err != nil {
+		log.Fatalf("unable to print %d: %v", h.ID, err)
+	}
+}
+
+// printArticleEntry prints h as ls --articles shows it: the ID that names it
+// within its feed, then its title.
+func printArticleEntry(h ttrss.Headline) {
+	fmt.Printf("%d\t%s\n", h.ID, display(h.Title))
+}
+
+// Space between adjacent columns in packColumns output.
+const columnGutter = 2
+
+// packColumns lays out names down-then-across in as many columns as fit
+// within width, the way ls(1) does. Each returned line lacks a trailing
+// newline and trailing spaces. If even two columns will not fit, every name
+// gets its own line.
+func packColumns(names []string, width int) (lines []string) {
+	n := len(names)
+	if n == 0 {
+		return
+	}
+
+	rows := n
+	var widths []int
+	for cols := n; cols > 1; cols-- {
+		tryRows := (n + cols - 1) / cols
+		// Filling column-major may need fewer columns than we asked for.
+		tryCols := (n + tryRows - 1) / tryRows
+		tryWidths := make([]int, tryCols)
+		total := columnGutter * (tryCols - 1)
+		for i, name := range names {
+			col := i / tryRows
+			if w := len([]rune(name)); w > tryWidths[col] {
+				total += w - tryWidths[col]
+				tryWidths[col] = w
+			}
+		}
+		if total <= width {
+			rows, widths = tryRows, tryWidths
+			break
+		}
+	}
+
+	for row := 0; row < rows; row++ {
+		line := ""
+		for col := 0; row+col*rows < n; col++ {
+			name := names[row+col*rows]
+			if col > 0 {
+				pad := widths[col-1] - len([]rune(names[row+(col-1)*rows]))
+				line += strings.Repeat(" ", pad+columnGutter)
+			}
+			line += name
+		}
+		lines = append(lines, line)
+	}
+	return
+}
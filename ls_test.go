@@ -0,0 +1,70 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPackColumns(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		width int
+		want  []string
+	}{
+		{"nothing", nil, 80, nil},
+		{"one row", []string{"a", "b", "c"}, 80, []string{"a  b  c"}},
+		{"exact fit", []string{"a", "b", "c"}, 7, []string{"a  b  c"}},
+		{"down then across", []string{"a", "b", "c"}, 4,
+			[]string{"a  c", "b"}},
+		{"too narrow for two", []string{"a", "b", "c"}, 3,
+			[]string{"a", "b", "c"}},
+		{"padded to widest in column",
+			[]string{"alpha", "b", "gamma", "d"}, 12,
+			[]string{"alpha  gamma", "b      d"}},
+		{"no trailing spaces", []string{"alpha", "b", "c"}, 10,
+			[]string{"alpha  c", "b"}},
+		{"runes, not bytes", []string{"é", "ab"}, 5, []string{"é  ab"}},
+	}
+	for _, test := range tests {
+		got := packColumns(test.names, test.width)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: packColumns(%q, %d) = %q, want %q", test.name,
+				test.names, test.width, got, test.want)
+		}
+	}
+}
+
+func TestLsLimit(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(
+			catItem(1, "Blogs", feedItem(10, "a"), feedItem(11, "b")),
+			catItem(2, "News", feedItem(12, "c"),
+				catItem(3, "Sub", feedItem(13, "d")))),
+	})
+
+	tests := []struct {
+		args       []string
+		want, note string
+	}{
+		{[]string{"--limit", "3"}, "Blogs\nNews\n", ""},
+		{[]string{"-R", "--limit", "3"}, "/:\nBlogs\nNews\n\n" +
+			"/Blogs:\na\n", "…(truncated, 4 more)\n"},
+		{[]string{"-R", "--limit", "4"}, "/:\nBlogs\nNews\n\n" +
+			"/Blogs:\na\nb\n", "…(truncated, 3 more)\n"},
+		{[]string{"-R", "--limit", "8"}, "/:\nBlogs\nNews\n\n" +
+			"/Blogs:\na\nb\n\n/News:\nc\nSub\n\n/News/Sub:\nd\n", ""},
+	}
+	for _, test := range tests {
+		args := append([]string{"ls", "-1"}, test.args...)
+		stdout, stderr, code := runTool(t, stub, "", args...)
+		if code != EX_SUCCESS || stdout != test.want ||
+			stderr != test.note {
+			t.Errorf("%q: got exit %d, stdout %q, stderr %q; "+
+				"want %q, %q", args, code, stdout, stderr, test.want,
+				test.note)
+		}
+	}
+}
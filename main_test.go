@@ -0,0 +1,280 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"ttrss"
+)
+
+// toolArgsEnv, when set, has the test binary run main with the arguments
+// it holds, one per line, rather than run the tests. runTool uses it to run
+// ttrss-tool as a whole, exit and all.
+const toolArgsEnv = "TTRSS_TOOL_TEST_ARGS"
+
+func TestMain(m *testing.M) {
+	if args, ok := os.LookupEnv(toolArgsEnv); ok {
+		os.Args = append([]string{"ttrss-tool"},
+			strings.Split(args, "\n")...)
+		main()
+		os.Exit(EX_SUCCESS)
+	}
+	os.Exit(m.Run())
+}
+
+// stubOp answers a call to an API op, given the request, with the content
+// of the response, or with a stubError for an error status.
+type stubOp func(req map[string]interface{}) interface{}
+
+// stubError is the error a stubOp answers with.
+type stubError string
+
+// stubCall is a request made of a stubServer.
+type stubCall struct {
+	Op  string
+	Req map[string]interface{}
+}
+
+// stubServer stands in for a Tiny Tiny RSS server. It answers API calls at
+// /api/ with its ops, logging in anyone, and serves its feeds by path.
+type stubServer struct {
+	*httptest.Server
+	ops   map[string]stubOp
+	feeds map[string]string
+
+	mu    sync.Mutex
+	calls []stubCall
+}
+
+// newStubServer starts a stubServer answering ops, closed when the test
+// ends.
+func newStubServer(t *testing.T, ops map[string]stubOp) *stubServer {
+	stub := &stubServer{ops: ops, feeds: make(map[string]string)}
+	stub.Server = httptest.NewServer(stub)
+	t.Cleanup(stub.Close)
+	return stub
+}
+
+func (stub *stubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/api/" {
+		feed, ok := stub.feeds[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		io.WriteString(w, feed)
+		return
+	}
+
+	var req map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	op, _ := req["op"].(string)
+	stub.mu.Lock()
+	stub.calls = append(stub.calls, stubCall{op, req})
+	stub.mu.Unlock()
+
+	var content interface{}
+	answer, ok := stub.ops[op]
+	switch {
+	case ok:
+		content = answer(req)
+	case op == "login":
+		content = map[string]interface{}{"session_id": "SID"}
+	case op == "isLoggedIn":
+		content = map[string]interface{}{"status": true}
+	default:
+		content = stubError("UNKNOWN_METHOD")
+	}
+	status := ttrss.API_STATUS_OK
+	if text, isErr := content.(stubError); isErr {
+		status = ttrss.API_STATUS_ERR
+		content = map[string]interface{}{"error": string(text)}
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"seq": 0, "status": status, "content": content})
+}
+
+// called returns the calls made to op, in order.
+func (stub *stubServer) called(op string) (calls []stubCall) {
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	for _, call := range stub.calls {
+		if call.Op == op {
+			calls = append(calls, call)
+		}
+	}
+	return
+}
+
+// useStub points tt at stub, logged in, until the test ends.
+func useStub(t *testing.T, stub *stubServer) {
+	saved := tt
+	tt = ttrss.Client{
+		ApiEP: ttrss.APIEndpoint(stub.URL), SessionID: "SID"}
+	t.Cleanup(func() { tt = saved })
+}
+
+// treeOp answers getFeedTree with a tree holding items, as made by
+// feedItem and catItem. With no items, it's a brand-new account's tree,
+// which has none at all rather than an empty list.
+func treeOp(items ...interface{}) stubOp {
+	return func(map[string]interface{}) interface{} {
+		categories := map[string]interface{}{"identifier": "id"}
+		if len(items) > 0 {
+			categories["items"] = items
+		}
+		return map[string]interface{}{"categories": categories}
+	}
+}
+
+func feedItem(id int, name string) interface{} {
+	return map[string]interface{}{
+		"bare_id": id, "name": name, "type": ttrss.Feed}
+}
+
+func catItem(id int, name string, items ...interface{}) interface{} {
+	if items == nil {
+		items = []interface{}{}
+	}
+	return map[string]interface{}{
+		"bare_id": id, "name": name, "type": ttrss.Category, "items": items}
+}
+
+func headlineItem(id, feedID int, title string) interface{} {
+	return map[string]interface{}{"id": id, "feed_id": feedID,
+		"title": title, "link": "http://example.com/", "score": 0,
+		"updated": 0}
+}
+
+// runTool runs ttrss-tool with args, logged in to stub, with a state dir of
+// its own and stdin as given, and returns what it wrote and its exit code.
+func runTool(t *testing.T, stub *stubServer, stdin string,
+	args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	cmd, out, errOut := startTool(t, stub, stdin, args...)
+	code = waitTool(t, cmd)
+	return out.String(), errOut.String(), code
+}
+
+// startTool starts ttrss-tool as runTool does, without waiting for it.
+// Its output is only complete once waitTool returns.
+func startTool(t *testing.T, stub *stubServer, stdin string,
+	args ...string) (cmd *exec.Cmd, stdout, stderr *bytes.Buffer) {
+	t.Helper()
+	args = append([]string{"--state-dir", t.TempDir(),
+		"-a", stub.URL, "-p", "pass"}, args...)
+	cmd = exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), toolArgsEnv+"="+strings.Join(args, "\n"))
+	cmd.Stdin = strings.NewReader(stdin)
+	stdout, stderr = new(bytes.Buffer), new(bytes.Buffer)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("running %q: %v", args, err)
+	}
+	return
+}
+
+// waitTool waits for ttrss-tool, started by startTool, to exit, and returns
+// its exit code.
+func waitTool(t *testing.T, cmd *exec.Cmd) (code int) {
+	t.Helper()
+	err := cmd.Wait()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr):
+		code = exitErr.ExitCode()
+	case err != nil:
+		t.Fatalf("running %q: %v", cmd.Env[len(cmd.Env)-1], err)
+	}
+	return
+}
+
+const stubRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Stub</title>
+<item><title>First</title><link>http://example.com/1</link></item>
+</channel></rss>
+`
+
+func TestEmptyTree(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{"getFeedTree": treeOp()})
+
+	stdout, stderr, code := runTool(t, stub, "", "ls", "/")
+	if code != EX_SUCCESS || stdout != "" ||
+		stderr != "(no categories)\n" {
+		t.Errorf("ls /: got exit %d, stdout %q, stderr %q; "+
+			"want exit 0 and only (no categories) on stderr",
+			code, stdout, stderr)
+	}
+
+	stdout, stderr, code = runTool(t, stub, "", "-q", "ls", "/")
+	if code != EX_SUCCESS || stdout != "" || stderr != "" {
+		t.Errorf("-q ls /: got exit %d, stdout %q, stderr %q; "+
+			"want exit 0 and no output", code, stdout, stderr)
+	}
+}
+
+func TestMkdirInEmptyTree(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(),
+		"addCategory": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"category_id": 7}
+		},
+	})
+
+	stdout, stderr, code := runTool(t, stub, "", "mkdir", "/Blogs")
+	if code != EX_SUCCESS || stdout != "7\t/Blogs\n" {
+		t.Errorf("mkdir /Blogs: got exit %d, stdout %q, stderr %q; "+
+			"want exit 0 and 7\\t/Blogs", code, stdout, stderr)
+	}
+	calls := stub.called("addCategory")
+	if len(calls) != 1 || calls[0].Req["caption"] != "Blogs" ||
+		calls[0].Req["parent_id"] != nil {
+		t.Errorf("mkdir /Blogs: got addCategory calls %+v, "+
+			"want one for a top-level Blogs", calls)
+	}
+}
+
+func TestLnParentsInEmptyTree(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(),
+		"addCategory": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"category_id": 7}
+		},
+		"subscribeToFeed": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"status": map[string]interface{}{
+				"code": int(ttrss.SUB_ADDED), "feed_id": 20}}
+		},
+	})
+	stub.feeds["/feed.xml"] = stubRSS
+	feedURL := stub.URL + "/feed.xml"
+
+	_, stderr, code := runTool(t, stub, "", "ln", "-p", feedURL, "/Blogs")
+	if code != EX_SUCCESS {
+		t.Fatalf("ln -p: got exit %d, stderr %q; want exit 0",
+			code, stderr)
+	}
+	if !strings.Contains(stderr, "created category /Blogs") {
+		t.Errorf("ln -p: got stderr %q; want it to say it created /Blogs",
+			stderr)
+	}
+	calls := stub.called("subscribeToFeed")
+	if len(calls) != 1 || calls[0].Req["feed_url"] != feedURL ||
+		calls[0].Req["category_id"] != 7.0 {
+		t.Errorf("ln -p: got subscribeToFeed calls %+v, "+
+			"want one for %s in category 7", calls, feedURL)
+	}
+}
@@ -0,0 +1,74 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ttrss"
+)
+
+type Mark struct {
+	flHelp bool
+	flags  flag.FlagSet
+}
+
+// markModes maps the states mark can put articles in to how it does that.
+var markModes = map[string]ttrss.UpdateMode{
+	"read":   ttrss.UPDATE_FALSE,
+	"unread": ttrss.UPDATE_TRUE,
+}
+
+func (mark *Mark) Init() {
+	mark.flags.Init("mark", flag.PanicOnError)
+
+	mark.flags.BoolVar(&mark.flHelp, "h", false, "help")
+	mark.flags.BoolVar(&mark.flHelp, "help", false, "help")
+}
+
+func (mark *Mark) Flags() *flag.FlagSet {
+	return &mark.flags
+}
+
+func (mark *Mark) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "mark read|unread article_id... "+
+		"-- mark articles read or unread")
+}
+
+// Run marks each article given by ID read or unread, as the first argument
+// says. An ID of "-" stands for the IDs read from stdin.
+// To mark whole feeds read, there's touch.
+// It exits EX_DATAERR if an ID is malformed, and EX_UNAVAILABLE if the
+// server refused. The server says nothing of IDs that name no article.
+func (mark *Mark) Run(args []string) {
+	mark.flags.Parse(args)
+
+	if mark.flHelp {
+		flagSetPrintUsage(mark.flags, os.Stdout, "mark")
+		exit(EX_SUCCESS)
+	}
+
+	mode, ok := markModes[mark.flags.Arg(0)]
+	if mark.flags.NArg() < 2 || !ok {
+		flagSetPrintUsage(mark.flags, os.Stderr, "mark")
+		exit(EX_USAGE)
+	}
+
+	ids, err := parseArticleIDs(mark.flags.Args()[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "mark:", err)
+		exit(EX_DATAERR)
+	}
+	if len(ids) == 0 {
+		exit(EX_SUCCESS)
+	}
+
+	if err := updateArticles("mark", ids, ttrss.FIELD_UNREAD,
+		mode); err != nil {
+		fmt.Fprintln(os.Stderr, "mark:", err)
+		exit(EX_UNAVAILABLE)
+	}
+	exit(EX_SUCCESS)
+}
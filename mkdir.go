@@ -0,0 +1,189 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ttrss"
+)
+
+type Mkdir struct {
+	flHelp     bool
+	flParents  bool
+	flAllowDup bool
+	flags      flag.FlagSet
+}
+
+func (mkdir *Mkdir) Init() {
+	mkdir.flags.Init("mkdir", flag.PanicOnError)
+
+	mkdir.flags.BoolVar(&mkdir.flHelp, "h", false, "help")
+	mkdir.flags.BoolVar(&mkdir.flHelp, "help", false, "help")
+
+	mkdir.flags.BoolVar(&mkdir.flParents, "p", false,
+		"create missing parent categories too, and accept existing ones")
+	mkdir.flags.BoolVar(&mkdir.flAllowDup, "allow-dup", false,
+		"create a category even beside a feed of the same name")
+}
+
+func (mkdir *Mkdir) Flags() *flag.FlagSet {
+	return &mkdir.flags
+}
+
+func (mkdir *Mkdir) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "mkdir [-p] [--allow-dup] catpath... -- create categories")
+}
+
+// Run creates the category at each catpath, printing the ID and catpath of
+// each category created.
+// It carries on past failures, and exits with the code for the last one:
+// EX_NOINPUT if a parent is missing (without -p), EX_DATAERR if the category
+// already exists (without -p), can't go there, or would sit beside a feed of
+// the same name (without --allow-dup), and EX_UNAVAILABLE if the server
+// refused.
+func (mkdir *Mkdir) Run(args []string) {
+	mkdir.flags.Parse(args)
+
+	if mkdir.flHelp {
+		flagSetPrintUsage(mkdir.flags, os.Stdout, "mkdir")
+		exit(EX_SUCCESS)
+	}
+
+	if mkdir.flags.NArg() < 1 {
+		flagSetPrintUsage(mkdir.flags, os.Stderr, "mkdir")
+		exit(EX_USAGE)
+	}
+
+	code := EX_SUCCESS
+	for _, catpath := range mkdir.flags.Args() {
+		_, err := makeCategory("mkdir", catpath, mkdir.flParents,
+			mkdir.flAllowDup, func(id int, created string) {
+				fmt.Printf("%d\t%s\n", id, display(created))
+			})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "mkdir:", err)
+			printCandidates(err)
+			code = mkdirExitCode(err)
+		}
+	}
+	exit(code)
+}
+
+// errCategoryExists is returned by makeCategory when, without -p, the
+// category asked for is already there.
+type errCategoryExists string
+
+func (catpath errCategoryExists) Error() string {
+	return fmt.Sprintf("category exists: %q", string(catpath))
+}
+
+// errNoCategoryHere is returned by makeCategory when asked to create a
+// category where there can be none, like within Special.
+type errNoCategoryHere string
+
+func (catpath errNoCategoryHere) Error() string {
+	return fmt.Sprintf("can't create categories within %q", string(catpath))
+}
+
+// errFeedExists is returned by makeCategory when, without allowDup, the
+// category asked for would sit beside a feed of the same name. The server
+// allows that, but it makes for catpaths that need a trailing / to tell
+// apart.
+type errFeedExists struct {
+	catpath, name string
+}
+
+func (exists errFeedExists) Error() string {
+	return fmt.Sprintf("%s: a feed named %q already exists here "+
+		"(use --allow-dup)", exists.catpath, exists.name)
+}
+
+func mkdirExitCode(err error) int {
+	switch err.(type) {
+	case *PathError:
+		return EX_NOINPUT
+	case errCategoryExists, errNoCategoryHere, errFeedExists:
+		return EX_DATAERR
+	}
+	return EX_UNAVAILABLE
+}
+
+// makeCategory creates the category at catpath, and with parents, any
+// missing categories above it, returning its ID. Each category created is
+// logged as made by op, and passed to created.
+// With parents, a category that's already there is fine, and it's its ID
+// that's returned. The root stands for Uncategorized, as it does for ln.
+// Unless allowDup, no category is created beside a feed of the same name.
+func makeCategory(op, catpath string, parents, allowDup bool,
+	created func(id int, catpath string)) (categoryID int, err error) {
+	tree, err := tt.GetFeedTree(true)
+	if err != nil {
+		return
+	}
+
+	parts := PathComponents(catpath)
+	if len(parts) == 0 {
+		if parents {
+			return ttrss.CATEGORY_UNCATEGORIZED, nil
+		}
+		return 0, errCategoryExists("/")
+	}
+
+	cat := &tree
+	sofar := ""
+	creating := false
+	for i, part := range parts {
+		last := i == len(parts)-1
+		if !cat.IsRoot() &&
+			(cat.IsVirtual() || cat.ID == ttrss.CATEGORY_UNCATEGORIZED) {
+			return 0, errNoCategoryHere(sofar)
+		}
+
+		sofar += "/" + ttrss.EscapePathComponent(part)
+
+		// Once we've started creating categories, there's nothing below to
+		// look for.
+		if !creating {
+			children := findChildren(cat, part, true)
+			if len(children) > 0 {
+				if last && !parents {
+					return 0, errCategoryExists(catpath)
+				}
+				cat = children[0]
+				continue
+			}
+			if !last && !parents {
+				return 0, newPathError(catpath, part, cat)
+			}
+			if !allowDup && len(findChildren(cat, part, false)) > 0 {
+				return 0, errFeedExists{sofar, part}
+			}
+		}
+
+		parentID := cat.ID
+		if cat.IsRoot() {
+			parentID = ttrss.CATEGORY_UNCATEGORIZED
+		}
+		id, err := tt.AddCategory(part, parentID)
+		creating = true
+
+		result := "ok"
+		if err != nil {
+			result = err.Error()
+		} else {
+			stats.affected++
+		}
+		logChange(ChangelogEntry{
+			Op: op, Path: sofar, ID: id, Result: result})
+		if err != nil {
+			return 0, err
+		}
+
+		created(id, sofar)
+		cat = &ttrss.FeedTreeItem{ID: id, Name: part, Type: ttrss.Category}
+	}
+	return cat.ID, nil
+}
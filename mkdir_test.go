@@ -0,0 +1,45 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import "testing"
+
+func TestMkdirBesideFeed(t *testing.T) {
+	tests := []struct {
+		args      []string
+		wantCode  int
+		wantAdded bool
+	}{
+		{[]string{"/News/Tech"}, EX_DATAERR, false},
+		{[]string{"-p", "/News/Tech/Go"}, EX_DATAERR, false},
+		{[]string{"--allow-dup", "/News/Tech"}, EX_SUCCESS, true},
+		{[]string{"/News/Science"}, EX_SUCCESS, true},
+	}
+	for _, test := range tests {
+		stub := newStubServer(t, map[string]stubOp{
+			"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "Tech"))),
+			"addCategory": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{"category_id": 7}
+			},
+		})
+
+		args := append([]string{"mkdir"}, test.args...)
+		_, stderr, code := runTool(t, stub, "", args...)
+		added := len(stub.called("addCategory")) > 0
+		if code != test.wantCode || added != test.wantAdded {
+			t.Errorf("%q: got exit %d, addCategory called: %v, stderr %q; "+
+				"want exit %d, called: %v", args, code, added, stderr,
+				test.wantCode, test.wantAdded)
+		}
+	}
+
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "Tech"))),
+	})
+	_, stderr, _ := runTool(t, stub, "", "mkdir", "/News/Tech")
+	want := "mkdir: /News/Tech: a feed named \"Tech\" already exists " +
+		"here (use --allow-dup)\n"
+	if stderr != want {
+		t.Errorf("mkdir /News/Tech: got stderr %q, want %q", stderr, want)
+	}
+}
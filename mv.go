@@ -0,0 +1,241 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"ttrss"
+)
+
+type Mv struct {
+	flHelp bool
+	flags  flag.FlagSet
+}
+
+func (mv *Mv) Init() {
+	mv.flags.Init("mv", flag.PanicOnError)
+
+	mv.flags.BoolVar(&mv.flHelp, "h", false, "help")
+	mv.flags.BoolVar(&mv.flHelp, "help", false, "help")
+}
+
+func (mv *Mv) Flags() *flag.FlagSet {
+	return &mv.flags
+}
+
+func (mv *Mv) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "mv catpath... category -- move feeds into a category")
+	fmt.Fprintln(w, "mv catpath new_name -- rename a feed or category")
+}
+
+// Run moves each feed named into the category named last, or, given just
+// one item and a new name for it, renames it.
+// If that category doesn't exist, nothing moves, and it exits EX_NOINPUT.
+// Otherwise it carries on past failures, and exits with the code for the
+// last one: EX_NOINPUT if nothing is at a path, EX_DATAERR if what is there
+// is not a feed, and EX_UNAVAILABLE if the server refused.
+func (mv *Mv) Run(args []string) {
+	mv.flags.Parse(args)
+
+	if mv.flHelp {
+		flagSetPrintUsage(mv.flags, os.Stdout, "mv")
+		exit(EX_SUCCESS)
+	}
+
+	argc := mv.flags.NArg()
+	if argc < 2 {
+		flagSetPrintUsage(mv.flags, os.Stderr, "mv")
+		exit(EX_USAGE)
+	}
+
+	destPath := mv.flags.Arg(argc - 1)
+	if argc == 2 {
+		srcPath := mv.flags.Arg(0)
+		src, err := ResolveCatPath(srcPath)
+		if err == nil {
+			destPath = besidePath(srcPath, destPath)
+		}
+		if err == nil && src.Type == ttrss.Category {
+			exit(renameCategory(srcPath, src, destPath))
+		}
+
+		// A feed is renamed, rather than moved, if there's no category by
+		// that name to move it into.
+		if err == nil && src.Type == ttrss.Feed && !src.IsVirtual() {
+			_, err = ResolveCatPath(asCategoryPath(destPath))
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				exit(renameFeed(srcPath, src, destPath))
+			}
+		}
+	}
+
+	dest, err := ResolveCatPath(asCategoryPath(destPath))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mv: no category to move into: %v\n", err)
+		printCandidates(err)
+		var pathErr *PathError
+		if errors.As(err, &pathErr) {
+			exit(EX_NOINPUT)
+		}
+		exit(EX_DATAERR)
+	}
+	if dest.IsVirtual() {
+		fmt.Fprintf(os.Stderr, "mv: can't move feeds into %q\n", destPath)
+		exit(EX_DATAERR)
+	}
+
+	code := EX_SUCCESS
+	for _, catpath := range mv.flags.Args()[:argc-1] {
+		item, err := ResolveCatPath(catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "mv:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		if item.Type != ttrss.Feed || item.IsVirtual() {
+			fmt.Fprintf(os.Stderr, "mv: not a feed: %q\n", catpath)
+			code = EX_DATAERR
+			continue
+		}
+
+		if err := moveFeed(catpath, item, destPath, dest); err != nil {
+			fmt.Fprintf(os.Stderr, "mv: %s: %v\n", catpath, err)
+			code = EX_UNAVAILABLE
+		}
+	}
+	exit(code)
+}
+
+// moveFeed moves feed, found at catpath, into the category dest, found at
+// destPath, and logs the change.
+func moveFeed(catpath string, feed *ttrss.FeedTreeItem,
+	destPath string, dest *ttrss.FeedTreeItem) error {
+	// The root stands for Uncategorized, as it does for ln.
+	err := tt.MoveFeed(feed.ID, dest.ID)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	to := asCategoryPath(destPath) + ttrss.EscapePathComponent(feed.Name)
+	logChange(ChangelogEntry{
+		Op: "mv", Path: catpath, ID: feed.ID, To: to, Result: result})
+	return err
+}
+
+// renameCategory renames cat, found at catpath, so that it's found at
+// destPath instead, and returns the exit code for how that went.
+// The server can't move a category, so destPath must be beside catpath.
+func renameCategory(catpath string, cat *ttrss.FeedTreeItem,
+	destPath string) int {
+	if cat.IsRoot() || cat.IsVirtual() ||
+		cat.ID == ttrss.CATEGORY_UNCATEGORIZED {
+		fmt.Fprintf(os.Stderr,
+			"mv: refusing to rename %q: not yours to rename\n", catpath)
+		return EX_DATAERR
+	}
+
+	parentPath, title, code := renamedTitle(catpath, destPath)
+	if code != EX_SUCCESS {
+		return code
+	}
+	destCatPath := asCategoryPath(parentPath) +
+		ttrss.EscapePathComponent(title) + "/"
+	if _, err := ResolveCatPath(destCatPath); err == nil {
+		fmt.Fprintf(os.Stderr, "mv: category exists: %q\n", destPath)
+		return EX_DATAERR
+	}
+
+	err := tt.RenameCategory(cat.ID, title)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	to := parentPath + ttrss.EscapePathComponent(title)
+	logChange(ChangelogEntry{
+		Op: "mv", Path: catpath, ID: cat.ID, To: to, Result: result})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mv: %s: %v\n", catpath, err)
+		return EX_UNAVAILABLE
+	}
+	return EX_SUCCESS
+}
+
+// renameFeed renames feed, found at catpath, so that it's found at destPath
+// instead, and returns the exit code for how that went.
+func renameFeed(catpath string, feed *ttrss.FeedTreeItem,
+	destPath string) int {
+	parentPath, title, code := renamedTitle(catpath, destPath)
+	if code != EX_SUCCESS {
+		return code
+	}
+
+	err := tt.RenameFeed(feed.ID, title)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	to := parentPath + ttrss.EscapePathComponent(title)
+	logChange(ChangelogEntry{
+		Op: "mv", Path: catpath, ID: feed.ID, To: to, Result: result})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "mv: %s: %v\n", catpath, err)
+		return EX_UNAVAILABLE
+	}
+	return EX_SUCCESS
+}
+
+// besidePath returns destPath as a catpath. Given just a name, that's the
+// name beside the item at catpath, rather than at the top level.
+func besidePath(catpath, destPath string) string {
+	if strings.HasPrefix(destPath, "/") ||
+		len(PathComponents(destPath)) != 1 {
+		return destPath
+	}
+	parentPath, _ := splitCatPath(catpath)
+	return parentPath + destPath
+}
+
+// renamedTitle works out the new title for the item at catpath from
+// destPath, which is either a bare name or a catpath beside catpath.
+// Renaming can't move an item, so for any other destPath, it complains and
+// returns a failing exit code.
+func renamedTitle(catpath, destPath string) (parentPath, title string,
+	code int) {
+	parentPath, _ = splitCatPath(catpath)
+	destParentPath, title := splitCatPath(destPath)
+	bare := !strings.HasPrefix(destPath, "/") &&
+		len(PathComponents(destPath)) == 1
+	if title == "" {
+		fmt.Fprintf(os.Stderr, "mv: can't rename %q to %q\n",
+			catpath, destPath)
+		return parentPath, "", EX_USAGE
+	}
+	if !bare && destParentPath != parentPath {
+		fmt.Fprintf(os.Stderr, "mv: can't move %q into %q: "+
+			"no such category, and renaming can't move it\n",
+			catpath, destParentPath)
+		return parentPath, "", EX_DATAERR
+	}
+	return parentPath, title, EX_SUCCESS
+}
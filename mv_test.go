@@ -0,0 +1,72 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import "testing"
+
+// okOp answers an op with a plain OK.
+func okOp(map[string]interface{}) interface{} {
+	return map[string]interface{}{"status": "OK"}
+}
+
+func TestMvBareNameIsBeside(t *testing.T) {
+	// Both trees have a Technology at the top level; only the second has
+	// one beside Tech and Go.
+	topOnly := treeOp(
+		catItem(1, "News", catItem(2, "Tech"), feedItem(10, "Go")),
+		catItem(3, "Technology"))
+	beside := treeOp(
+		catItem(1, "News", catItem(2, "Tech"), feedItem(10, "Go"),
+			catItem(4, "Technology")),
+		catItem(3, "Technology"))
+
+	tests := []struct {
+		name    string
+		tree    stubOp
+		src     string
+		want    int
+		wantOp  string // the op that should be called, if any
+		wantKey string
+		wantVal interface{}
+	}{
+		{"category, top-level namesake", topOnly, "/News/Tech",
+			EX_SUCCESS, "renameCategory", "caption", "Technology"},
+		{"category, sibling namesake", beside, "/News/Tech",
+			EX_DATAERR, "", "", nil},
+		{"feed, top-level namesake", topOnly, "/News/Go",
+			EX_SUCCESS, "renameFeed", "title", "Technology"},
+		{"feed, sibling namesake", beside, "/News/Go",
+			EX_SUCCESS, "moveFeed", "category_id", 4.0},
+	}
+	for _, test := range tests {
+		stub := newStubServer(t, map[string]stubOp{
+			"getFeedTree":    test.tree,
+			"renameCategory": okOp,
+			"renameFeed":     okOp,
+			"moveFeed":       okOp,
+		})
+
+		_, stderr, code := runTool(t, stub, "", "mv", test.src,
+			"Technology")
+		if code != test.want {
+			t.Errorf("%s: got exit %d, stderr %q; want exit %d",
+				test.name, code, stderr, test.want)
+		}
+		for _, op := range []string{"renameCategory", "renameFeed",
+			"moveFeed"} {
+			calls := stub.called(op)
+			if op != test.wantOp {
+				if len(calls) != 0 {
+					t.Errorf("%s: got %s calls %+v, want none",
+						test.name, op, calls)
+				}
+				continue
+			}
+			if len(calls) != 1 ||
+				calls[0].Req[test.wantKey] != test.wantVal {
+				t.Errorf("%s: got %s calls %+v, want one with %s %v",
+					test.name, op, calls, test.wantKey, test.wantVal)
+			}
+		}
+	}
+}
@@ -0,0 +1,351 @@
+<?php
+// vi: set noet ts=4 sw=4 ft=php tw=79:
+
+// Ttrss_Tool provides the API ops that ttrss-tool needs but the stock API
+// lacks, as described under "Plugin API" in API.md.
+//
+// PluginHost only takes API ops from system plugins, so this has to be
+// enabled for the whole instance: copy this directory into plugins.local/,
+// and add ttrss_tool to TTRSS_PLUGINS (PLUGINS in an older config.php).
+//
+// Each op acts only on what belongs to the logged-in user, and answers as
+// the stock ops do: {"status": "OK", ...} or {"error": "SOME_CODE"}.
+class Ttrss_Tool extends Plugin {
+
+	function about() {
+		return array(1.0,
+			"API ops for ttrss-tool: manage categories and more",
+			"jeremy-w",
+			true,
+			"https://github.com/jeremy-w/ttrss-tool");
+	}
+
+	function api_version() {
+		return 2;
+	}
+
+	function init($host) {
+		$host->add_api_method("removeCategory", $this);
+		$host->add_api_method("addCategory", $this);
+		$host->add_api_method("moveFeed", $this);
+		$host->add_api_method("renameCategory", $this);
+		$host->add_api_method("renameFeed", $this);
+		$host->add_api_method("addLabel", $this);
+		$host->add_api_method("removeLabel", $this);
+		$host->add_api_method("getArticleTags", $this);
+		$host->add_api_method("purgeFeed", $this);
+		$host->add_api_method("setArticleTags", $this);
+	}
+
+	// removeCategory category_id: int
+	// Removes an empty category. One holding feeds or other categories is
+	// left alone, with CATEGORY_NOT_EMPTY.
+	function removeCategory() {
+		$cat_id = (int) ($_REQUEST["category_id"] ?? 0);
+		$owner_uid = $_SESSION["uid"];
+
+		if (!$this->ownsCategory($cat_id)) {
+			return $this->error("CATEGORY_NOT_FOUND");
+		}
+
+		$sth = $this->pdo->prepare("SELECT
+				(SELECT COUNT(*) FROM ttrss_feeds
+					WHERE cat_id = ? AND owner_uid = ?) +
+				(SELECT COUNT(*) FROM ttrss_feed_categories
+					WHERE parent_cat = ? AND owner_uid = ?) AS contents");
+		$sth->execute([$cat_id, $owner_uid, $cat_id, $owner_uid]);
+		$row = $sth->fetch();
+		if ($row && $row["contents"] > 0) {
+			return $this->error("CATEGORY_NOT_EMPTY");
+		}
+
+		$sth = $this->pdo->prepare("DELETE FROM ttrss_feed_categories
+			WHERE id = ? AND owner_uid = ?");
+		$sth->execute([$cat_id, $owner_uid]);
+		return $this->ok();
+	}
+
+	// addCategory caption: string, parent_id: int
+	// Creates a category within the one with ID parent_id, or at the top
+	// level if that's left out or 0, and answers with its category_id.
+	// A category of the same name already there is CATEGORY_EXISTS.
+	function addCategory() {
+		$caption = clean($_REQUEST["caption"] ?? "");
+		$parent_id = (int) ($_REQUEST["parent_id"] ?? 0);
+
+		if ($caption === "") {
+			return $this->error("INCORRECT_USAGE");
+		}
+		if ($parent_id && !$this->ownsCategory($parent_id)) {
+			return $this->error("CATEGORY_NOT_FOUND");
+		}
+		if ($this->findCategory($caption, $parent_id) !== false) {
+			return $this->error("CATEGORY_EXISTS");
+		}
+
+		$sth = $this->pdo->prepare("INSERT INTO ttrss_feed_categories
+			(owner_uid, title, parent_cat, order_id) VALUES (?, ?, ?, 0)");
+		$sth->execute([$_SESSION["uid"], $caption,
+			$parent_id ? $parent_id : null]);
+
+		$cat_id = $this->findCategory($caption, $parent_id);
+		if ($cat_id === false) {
+			return $this->error("CATEGORY_NOT_FOUND");
+		}
+		return $this->ok(array("category_id" => $cat_id));
+	}
+
+	// moveFeed feed_id: int, category_id: int
+	// Files a feed under a category, or Uncategorized if category_id is 0.
+	function moveFeed() {
+		$feed_id = (int) ($_REQUEST["feed_id"] ?? 0);
+		$cat_id = (int) ($_REQUEST["category_id"] ?? 0);
+
+		if (!$this->ownsFeed($feed_id)) {
+			return $this->error("FEED_NOT_FOUND");
+		}
+		if ($cat_id && !$this->ownsCategory($cat_id)) {
+			return $this->error("CATEGORY_NOT_FOUND");
+		}
+
+		$sth = $this->pdo->prepare("UPDATE ttrss_feeds SET cat_id = ?
+			WHERE id = ? AND owner_uid = ?");
+		$sth->execute([$cat_id ? $cat_id : null, $feed_id, $_SESSION["uid"]]);
+		return $this->ok();
+	}
+
+	// renameCategory category_id: int, caption: string
+	// Retitles a category, leaving it where it is.
+	function renameCategory() {
+		$cat_id = (int) ($_REQUEST["category_id"] ?? 0);
+		$caption = clean($_REQUEST["caption"] ?? "");
+
+		if ($caption === "") {
+			return $this->error("INCORRECT_USAGE");
+		}
+		if (!$this->ownsCategory($cat_id)) {
+			return $this->error("CATEGORY_NOT_FOUND");
+		}
+
+		$sth = $this->pdo->prepare("UPDATE ttrss_feed_categories SET title = ?
+			WHERE id = ? AND owner_uid = ?");
+		$sth->execute([$caption, $cat_id, $_SESSION["uid"]]);
+		return $this->ok();
+	}
+
+	// renameFeed feed_id: int, title: string
+	// Sets a feed's title, as the feed editor does. Updates don't change it
+	// back: the server only takes a feed's own title when subscribing.
+	function renameFeed() {
+		$feed_id = (int) ($_REQUEST["feed_id"] ?? 0);
+		$title = clean($_REQUEST["title"] ?? "");
+
+		if ($title === "") {
+			return $this->error("INCORRECT_USAGE");
+		}
+		if (!$this->ownsFeed($feed_id)) {
+			return $this->error("FEED_NOT_FOUND");
+		}
+
+		$sth = $this->pdo->prepare("UPDATE ttrss_feeds SET title = ?
+			WHERE id = ? AND owner_uid = ?");
+		$sth->execute([$title, $feed_id, $_SESSION["uid"]]);
+		return $this->ok();
+	}
+
+	// addLabel caption: string
+	// Creates a label, and answers with its label_id, given as getLabels
+	// gives it: the ID of the label's feed. A label of the same name, in
+	// any case, is LABEL_EXISTS, as the server won't tell them apart.
+	function addLabel() {
+		$caption = clean($_REQUEST["caption"] ?? "");
+
+		if ($caption === "") {
+			return $this->error("INCORRECT_USAGE");
+		}
+		if ($this->findLabel($caption) !== false) {
+			return $this->error("LABEL_EXISTS");
+		}
+
+		$sth = $this->pdo->prepare("INSERT INTO ttrss_labels2
+			(owner_uid, caption) VALUES (?, ?)");
+		$sth->execute([$_SESSION["uid"], $caption]);
+
+		$label_id = $this->findLabel($caption);
+		if ($label_id === false) {
+			return $this->error("LABEL_NOT_FOUND");
+		}
+		return $this->ok(array(
+			"label_id" => Labels::label_to_feed_id($label_id)));
+	}
+
+	// removeLabel label_id: int
+	// Deletes a label, given as getLabels gives it, taking it off every
+	// article that has it.
+	function removeLabel() {
+		$label_id = Labels::feed_to_label_id(
+			(int) ($_REQUEST["label_id"] ?? 0));
+		$owner_uid = $_SESSION["uid"];
+
+		$sth = $this->pdo->prepare("SELECT id FROM ttrss_labels2
+			WHERE id = ? AND owner_uid = ?");
+		$sth->execute([$label_id, $owner_uid]);
+		if (!$sth->fetch()) {
+			return $this->error("LABEL_NOT_FOUND");
+		}
+
+		// This also drops the label from articles and filters, and from
+		// the cached label lists articles keep.
+		Labels::remove($label_id, $owner_uid);
+		return $this->ok();
+	}
+
+	// purgeFeed feed_id: int, days: int
+	// Deletes a feed's articles older than days days, as the update daemon
+	// does with the feed's purge interval, and answers with how many went.
+	// The server's own purging keeps starred articles, and unread ones
+	// unless the user's preferences say otherwise.
+	function purgeFeed() {
+		$feed_id = (int) ($_REQUEST["feed_id"] ?? 0);
+		$days = (int) ($_REQUEST["days"] ?? 0);
+
+		if ($days <= 0) {
+			return $this->error("INCORRECT_USAGE");
+		}
+		if (!$this->ownsFeed($feed_id)) {
+			return $this->error("FEED_NOT_FOUND");
+		}
+
+		// Newer servers moved purge_feed into Feeds.
+		if (method_exists("Feeds", "_purge")) {
+			$purged = Feeds::_purge($feed_id, $days);
+		} else {
+			$purged = purge_feed($feed_id, $days);
+		}
+		return $this->ok(array("purged" => (int) $purged));
+	}
+
+	// getArticleTags article_id: int
+	// Answers with the tags on an article, in alphabetical order.
+	function getArticleTags() {
+		$int_id = $this->findUserEntry((int) ($_REQUEST["article_id"] ?? 0));
+
+		if ($int_id === false) {
+			return $this->error("ARTICLE_NOT_FOUND");
+		}
+
+		$sth = $this->pdo->prepare("SELECT DISTINCT tag_name FROM ttrss_tags
+			WHERE post_int_id = ? AND owner_uid = ? ORDER BY tag_name");
+		$sth->execute([$int_id, $_SESSION["uid"]]);
+		$tags = array();
+		while ($row = $sth->fetch()) {
+			$tags[] = $row["tag_name"];
+		}
+		return $this->ok(array("tags" => $tags));
+	}
+
+	// setArticleTags article_id: int, tags: string
+	// Replaces the tags on an article with those in the comma-separated list
+	// tags, as the web UI's tag editor does: trimmed, lowercased, and each
+	// once. An empty list leaves the article untagged.
+	function setArticleTags() {
+		$int_id = $this->findUserEntry((int) ($_REQUEST["article_id"] ?? 0));
+		$owner_uid = $_SESSION["uid"];
+
+		if ($int_id === false) {
+			return $this->error("ARTICLE_NOT_FOUND");
+		}
+
+		$tags = array();
+		foreach (explode(",", clean($_REQUEST["tags"] ?? "")) as $tag) {
+			$tag = mb_strtolower(trim($tag));
+			if ($tag !== "" && !in_array($tag, $tags)) {
+				$tags[] = $tag;
+			}
+		}
+
+		$this->pdo->beginTransaction();
+
+		$sth = $this->pdo->prepare("DELETE FROM ttrss_tags
+			WHERE post_int_id = ? AND owner_uid = ?");
+		$sth->execute([$int_id, $owner_uid]);
+
+		$sth = $this->pdo->prepare("INSERT INTO ttrss_tags
+			(post_int_id, owner_uid, tag_name) VALUES (?, ?, ?)");
+		foreach ($tags as $tag) {
+			$sth->execute([$int_id, $owner_uid, $tag]);
+		}
+
+		// Headlines show tags from this cache, not from ttrss_tags.
+		$sth = $this->pdo->prepare("UPDATE ttrss_user_entries
+			SET tag_cache = ? WHERE int_id = ? AND owner_uid = ?");
+		$sth->execute([implode(",", $tags), $int_id, $owner_uid]);
+
+		$this->pdo->commit();
+		return $this->ok();
+	}
+
+	// findCategory returns the ID of the user's category titled title
+	// within the one with ID parent_id, or at the top level if that's 0, or
+	// false if there's none.
+	private function findCategory($title, $parent_id) {
+		if ($parent_id) {
+			$sth = $this->pdo->prepare("SELECT id FROM ttrss_feed_categories
+				WHERE title = ? AND parent_cat = ? AND owner_uid = ?");
+			$sth->execute([$title, $parent_id, $_SESSION["uid"]]);
+		} else {
+			$sth = $this->pdo->prepare("SELECT id FROM ttrss_feed_categories
+				WHERE title = ? AND parent_cat IS NULL AND owner_uid = ?");
+			$sth->execute([$title, $_SESSION["uid"]]);
+		}
+		$row = $sth->fetch();
+		return $row ? (int) $row["id"] : false;
+	}
+
+	// findLabel returns the ID, in ttrss_labels2, of the user's label
+	// captioned caption in any case, or false if there's none.
+	private function findLabel($caption) {
+		$sth = $this->pdo->prepare("SELECT id FROM ttrss_labels2
+			WHERE LOWER(caption) = LOWER(?) AND owner_uid = ?");
+		$sth->execute([$caption, $_SESSION["uid"]]);
+		$row = $sth->fetch();
+		return $row ? (int) $row["id"] : false;
+	}
+
+	// findUserEntry returns the ID, in ttrss_user_entries, of the user's
+	// copy of the article with ID article_id, which is what tags hang off,
+	// or false if they have none.
+	private function findUserEntry($article_id) {
+		$sth = $this->pdo->prepare("SELECT int_id FROM ttrss_user_entries
+			WHERE ref_id = ? AND owner_uid = ?");
+		$sth->execute([$article_id, $_SESSION["uid"]]);
+		$row = $sth->fetch();
+		return $row ? (int) $row["int_id"] : false;
+	}
+
+	// ownsCategory reports whether the user has a category with ID cat_id.
+	// Uncategorized, 0, is no category of theirs: it's built in.
+	private function ownsCategory($cat_id) {
+		$sth = $this->pdo->prepare("SELECT id FROM ttrss_feed_categories
+			WHERE id = ? AND owner_uid = ?");
+		$sth->execute([$cat_id, $_SESSION["uid"]]);
+		return (bool) $sth->fetch();
+	}
+
+	// ownsFeed reports whether the user subscribes to a feed with ID feed_id.
+	private function ownsFeed($feed_id) {
+		$sth = $this->pdo->prepare("SELECT id FROM ttrss_feeds
+			WHERE id = ? AND owner_uid = ?");
+		$sth->execute([$feed_id, $_SESSION["uid"]]);
+		return (bool) $sth->fetch();
+	}
+
+	private function ok($content = array()) {
+		return array(API::STATUS_OK,
+			array_merge(array("status" => "OK"), $content));
+	}
+
+	private function error($code) {
+		return array(API::STATUS_ERR, array("error" => $code));
+	}
+}
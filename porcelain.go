@@ -0,0 +1,55 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The porcelain formats there are. Once released, a format never changes;
+// a change means a new version.
+const porcelainV1 = "v1"
+
+// porcelainValue is a flag.Value for --porcelain, which asks for output
+// meant for scripts rather than people, in the format version given, as in
+// --porcelain=v1. Given no version, it means the first.
+type porcelainValue string
+
+func (porcelain *porcelainValue) String() string {
+	return string(*porcelain)
+}
+
+func (porcelain *porcelainValue) Set(text string) error {
+	if text == "true" {
+		text = porcelainV1
+	}
+	if text != porcelainV1 {
+		return fmt.Errorf("unknown porcelain format %q (want %q)",
+			text, porcelainV1)
+	}
+	*porcelain = porcelainValue(text)
+	return nil
+}
+
+// IsBoolFlag lets --porcelain go without a version.
+func (porcelain *porcelainValue) IsBoolFlag() bool {
+	return true
+}
+
+// porcelainUsage describes --porcelain to each command's flags.
+const porcelainUsage = "print tab-separated output for scripts " +
+	"(--porcelain=v1, the default, is the only format so far)"
+
+// printPorcelain prints fields, separated by tabs, as a line of porcelain
+// output. Each field is escaped, whatever --raw-names says: backslashes are
+// doubled, and tabs, newlines, and other control characters are escaped as
+// display escapes them.
+func printPorcelain(w io.Writer, fields ...string) {
+	escaped := make([]string, len(fields))
+	for i, field := range fields {
+		escaped[i] = escapeControls(strings.Replace(field, `\`, `\\`, -1))
+	}
+	fmt.Fprintln(w, strings.Join(escaped, "\t"))
+}
@@ -0,0 +1,112 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+// porcelainOps answer everything ls, du, and stat ask, for a tree whose
+// names need escaping: a category with a tab in its name, holding a feed
+// with a backslash in its name and a newline in its last error.
+func porcelainOps() map[string]stubOp {
+	broken := map[string]interface{}{"bare_id": 10, "name": `Back\slash`,
+		"type": "feed", "error": "boom\nagain"}
+	return map[string]stubOp{
+		"getFeedTree": treeOp(
+			catItem(-1, "Special", feedItem(-4, "All articles")),
+			catItem(1, "Tab\there", broken, feedItem(11, "Plain"))),
+		"getCounters": func(map[string]interface{}) interface{} {
+			return []interface{}{
+				map[string]interface{}{"id": 10, "counter": 3},
+				map[string]interface{}{"id": 11, "counter": 4},
+				map[string]interface{}{"id": -4, "counter": 7},
+			}
+		},
+		"getFeeds": func(map[string]interface{}) interface{} {
+			return []interface{}{
+				map[string]interface{}{"id": 10, "title": `Back\slash`,
+					"feed_url": "http://example.com/a\tb", "cat_id": 1,
+					"unread": 3, "last_updated": 1700000000},
+				map[string]interface{}{"id": 11, "title": "Plain",
+					"feed_url": "http://example.com/plain", "cat_id": 1,
+					"unread": 4, "last_updated": 0},
+			}
+		},
+	}
+}
+
+// TestPorcelainGolden pins the porcelain output, field order and escaping
+// and all. It must never change: a change needs a new format version.
+func TestPorcelainGolden(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ls", "--porcelain", "-a", "/"},
+			"category\t-1\t1\t\t0\t/Special\t\t\n" +
+				"category\t1\t0\t7\t0\t/Tab\\there\t\t\n"},
+		{[]string{"ls", "--porcelain", "/Tab\there"},
+			"feed\t10\t0\t3\t1700000000\t/Tab\\there/Back\\\\slash\t" +
+				"http://example.com/a\\tb\tboom\\nagain\n" +
+				"feed\t11\t0\t4\t0\t/Tab\\there/Plain\t" +
+				"http://example.com/plain\t\n"},
+		{[]string{"du", "--porcelain", "-a", "-c", "/Tab\there"},
+			"3\t/Tab\\there/Back\\\\slash\n" +
+				"4\t/Tab\\there/Plain\n" +
+				"7\t/Tab\\there\n" +
+				"7\t\n"},
+		{[]string{"stat", "--porcelain", "/Tab\there/Back\\slash",
+			"/Tab\there"},
+			"path\t/Tab\\there/Back\\\\slash\n" +
+				"type\tfeed\n" +
+				"id\t10\n" +
+				"url\thttp://example.com/a\\tb\n" +
+				"category\t/Tab\\there\n" +
+				"updated\t1700000000\n" +
+				"error\tboom\\nagain\n" +
+				"unread\t3\n" +
+				"\n" +
+				"path\t/Tab\\there\n" +
+				"type\tcategory\n" +
+				"id\t1\n" +
+				"categories\t0\n" +
+				"feeds\t2\n" +
+				"unread\t7\n"},
+	}
+	for _, test := range tests {
+		stub := newStubServer(t, porcelainOps())
+		stdout, stderr, code := runTool(t, stub, "", test.args...)
+		if code != EX_SUCCESS || stdout != test.want {
+			t.Errorf("%q: got exit %d, stderr %q, stdout:\n%q\nwant:\n%q",
+				test.args, code, stderr, stdout, test.want)
+		}
+	}
+}
+
+func TestPorcelainGoldenConfigCheck(t *testing.T) {
+	stub := newStubServer(t, nil)
+	useStub(t, stub)
+	savedAddr, savedUser, savedPass, savedDotfile :=
+		flAddr, flUser, flPass, flDotfilePath
+	defer func() {
+		flAddr, flUser, flPass, flDotfilePath =
+			savedAddr, savedUser, savedPass, savedDotfile
+	}()
+	flAddr, flUser, flPass = stub.URL, "me\there", "pass"
+	flDotfilePath = filepath.Join(t.TempDir(), "config")
+
+	var out bytes.Buffer
+	if code := checkConfig(&out, true); code != EX_SUCCESS {
+		t.Fatalf("checkConfig = %d; reported:\n%s", code, &out)
+	}
+	want := "ok\tdotfile\tnone at " + flDotfilePath + "\n" +
+		"ok\taddr\t" + stub.URL + "\n" +
+		"ok\tserver\t" + stub.URL + "/api/\n" +
+		"ok\tlogin\tas me\\there\n"
+	if got := out.String(); got != want {
+		t.Errorf("checkConfig porcelain:\n%q\nwant:\n%q", got, want)
+	}
+}
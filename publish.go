@@ -0,0 +1,74 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ttrss"
+)
+
+type Publish struct {
+	flHelp      bool
+	flUnpublish bool
+	flags       flag.FlagSet
+}
+
+func (publish *Publish) Init() {
+	publish.flags.Init("publish", flag.PanicOnError)
+
+	publish.flags.BoolVar(&publish.flHelp, "h", false, "help")
+	publish.flags.BoolVar(&publish.flHelp, "help", false, "help")
+
+	publish.flags.BoolVar(&publish.flUnpublish, "u", false,
+		"unpublish the articles")
+}
+
+func (publish *Publish) Flags() *flag.FlagSet {
+	return &publish.flags
+}
+
+func (publish *Publish) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "publish [-u] article_id... -- publish articles")
+}
+
+// Run publishes each article given by ID, so that it shows up in the
+// "Published articles" feed that the server shares, or with -u, unpublishes
+// it. An ID of "-" stands for the IDs read from stdin.
+// It exits EX_DATAERR if an ID is malformed, and EX_UNAVAILABLE if the
+// server refused. The server says nothing of IDs that name no article.
+func (publish *Publish) Run(args []string) {
+	publish.flags.Parse(args)
+
+	if publish.flHelp {
+		flagSetPrintUsage(publish.flags, os.Stdout, "publish")
+		exit(EX_SUCCESS)
+	}
+
+	if publish.flags.NArg() < 1 {
+		flagSetPrintUsage(publish.flags, os.Stderr, "publish")
+		exit(EX_USAGE)
+	}
+
+	ids, err := parseArticleIDs(publish.flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "publish:", err)
+		exit(EX_DATAERR)
+	}
+	if len(ids) == 0 {
+		exit(EX_SUCCESS)
+	}
+
+	mode := ttrss.UPDATE_TRUE
+	if publish.flUnpublish {
+		mode = ttrss.UPDATE_FALSE
+	}
+	if err := updateArticles("publish", ids, ttrss.FIELD_PUBLISHED,
+		mode); err != nil {
+		fmt.Fprintln(os.Stderr, "publish:", err)
+		exit(EX_UNAVAILABLE)
+	}
+	exit(EX_SUCCESS)
+}
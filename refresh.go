@@ -0,0 +1,248 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"ttrss"
+)
+
+type Refresh struct {
+	flHelp        bool
+	flRecurse     bool
+	flWait        bool
+	flWaitTimeout time.Duration
+	flInterval    time.Duration
+	flags         flag.FlagSet
+
+	// refreshed lists the feeds asked to refresh, for --wait to wait on.
+	refreshed []refreshedFeed
+}
+
+// refreshedFeed is a feed the server was asked to refresh.
+type refreshedFeed struct {
+	catpath string
+	id      int
+}
+
+func (refresh *Refresh) Init() {
+	refresh.flags.Init("refresh", flag.PanicOnError)
+
+	refresh.flags.BoolVar(&refresh.flHelp, "h", false, "help")
+	refresh.flags.BoolVar(&refresh.flHelp, "help", false, "help")
+
+	recurseHelp := "refresh every feed in categories"
+	refresh.flags.BoolVar(&refresh.flRecurse, "r", false, recurseHelp)
+	refresh.flags.BoolVar(&refresh.flRecurse, "R", false, recurseHelp)
+
+	refresh.flags.BoolVar(&refresh.flWait, "wait", false,
+		"wait for the server to finish refreshing each feed")
+	refresh.flags.DurationVar(&refresh.flWaitTimeout, "wait-timeout",
+		2*time.Minute, "with --wait, give up waiting after `D`")
+	refresh.flags.DurationVar(&refresh.flInterval, "interval",
+		5*time.Second, "with --wait, check on the feeds every `D`")
+}
+
+func (refresh *Refresh) Flags() *flag.FlagSet {
+	return &refresh.flags
+}
+
+func (refresh *Refresh) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "refresh [-R] [--wait [--wait-timeout D] [--interval D]] "+
+		"catpath... -- fetch feeds now")
+}
+
+// Run asks the server to fetch each feed named now, rather than when its
+// update daemon next gets around to it. The server may do that in the
+// background, so new articles can take a while to show up.
+// It carries on past failures, and exits with the code for the last one:
+// EX_NOINPUT if nothing is at the path, EX_DATAERR if what is there is
+// a category and -R wasn't given, or is a virtual feed, which has nothing to
+// fetch, and EX_UNAVAILABLE if the server refused.
+// With -R, every feed in a category and those below it is refreshed.
+// With --wait, it then checks on the feeds every --interval until the
+// server has updated each, printing each as it is, and gives up on the rest
+// after --wait-timeout, exiting EX_TEMPFAIL.
+func (refresh *Refresh) Run(args []string) {
+	refresh.flags.Parse(args)
+
+	if refresh.flHelp {
+		flagSetPrintUsage(refresh.flags, os.Stdout, "refresh")
+		exit(EX_SUCCESS)
+	}
+
+	if refresh.flags.NArg() < 1 {
+		flagSetPrintUsage(refresh.flags, os.Stderr, "refresh")
+		exit(EX_USAGE)
+	}
+	if refresh.flInterval <= 0 {
+		fmt.Fprintln(os.Stderr, "refresh: --interval must be positive")
+		exit(EX_USAGE)
+	}
+
+	// Note when each feed was last updated, to know when it's been again.
+	var before map[int]time.Time
+	if refresh.flWait {
+		var err error
+		before, err = lastUpdated()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "refresh:", err)
+			exit(EX_UNAVAILABLE)
+		}
+	}
+
+	code := EX_SUCCESS
+	for _, catpath := range refresh.flags.Args() {
+		item, err := ResolveCatPath(catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "refresh:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		itemCode := refresh.refresh(catpath, item)
+		if itemCode != EX_SUCCESS {
+			code = itemCode
+		}
+	}
+	if refresh.flWait && len(refresh.refreshed) > 0 {
+		if waitCode := refresh.wait(before); waitCode != EX_SUCCESS {
+			code = waitCode
+		}
+	}
+	exit(code)
+}
+
+// wait checks on the feeds refreshed every --interval until each has been
+// updated since before, printing each as it is, and reports the rest as
+// timed out once --wait-timeout passes. It returns the exit code for how
+// that went.
+func (refresh *Refresh) wait(before map[int]time.Time) int {
+	pending := refresh.refreshed
+	deadline := time.Now().Add(refresh.flWaitTimeout)
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		nap := refresh.flInterval
+		if left := time.Until(deadline); left < nap {
+			nap = left
+		}
+		time.Sleep(nap)
+
+		now, err := lastUpdated()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "refresh:", err)
+			return EX_UNAVAILABLE
+		}
+		var still []refreshedFeed
+		for _, feed := range pending {
+			if now[feed.id].After(before[feed.id]) {
+				fmt.Printf("updated %s\n", display(feed.catpath))
+				continue
+			}
+			still = append(still, feed)
+		}
+		pending = still
+	}
+
+	for _, feed := range pending {
+		fmt.Fprintf(os.Stderr, "refresh: %s: timed out after %v waiting "+
+			"for the update\n", feed.catpath, refresh.flWaitTimeout)
+	}
+	if len(pending) > 0 {
+		return EX_TEMPFAIL
+	}
+	return EX_SUCCESS
+}
+
+// lastUpdated maps the ID of every feed to when the server last updated it.
+func lastUpdated() (updated map[int]time.Time, err error) {
+	feeds, err := tt.GetFeeds(ttrss.CATEGORY_FEEDS_NOT_VIRTUAL, false, false)
+	if err != nil {
+		return
+	}
+	updated = make(map[int]time.Time)
+	for _, feed := range feeds {
+		updated[feed.ID] = feed.LastUpdated
+	}
+	return
+}
+
+// refresh refreshes item, found at catpath, and returns the exit code for
+// how that went.
+func (refresh *Refresh) refresh(catpath string,
+	item *ttrss.FeedTreeItem) int {
+	if item.IsVirtual() {
+		fmt.Fprintf(os.Stderr,
+			"refresh: %q is virtual: the server has nothing to fetch\n",
+			catpath)
+		return EX_DATAERR
+	}
+	if item.Type == ttrss.Feed {
+		if err := refresh.update(catpath, item); err != nil {
+			fmt.Fprintf(os.Stderr, "refresh: %s: %v\n", catpath, err)
+			return EX_UNAVAILABLE
+		}
+		return EX_SUCCESS
+	}
+
+	if !refresh.flRecurse {
+		fmt.Fprintf(os.Stderr,
+			"refresh: not a feed: %q is a category (use -R)\n", catpath)
+		return EX_DATAERR
+	}
+
+	// There's no op to update a whole category, so update each feed in
+	// turn. Special and Labels only hold copies of those.
+	code := EX_SUCCESS
+	walkCatPath(item, catpath, func(item *ttrss.FeedTreeItem,
+		catpath string) bool {
+		if item.IsVirtual() {
+			return false
+		}
+		if item.Type == ttrss.Feed {
+			if err := refresh.update(catpath, item); err != nil {
+				fmt.Fprintf(os.Stderr, "refresh: %s: %v\n", catpath, err)
+				code = EX_UNAVAILABLE
+			}
+		}
+		return true
+	})
+	return code
+}
+
+// update asks the server to fetch the feed item, found at catpath, noting it
+// for --wait.
+func (refresh *Refresh) update(catpath string,
+	item *ttrss.FeedTreeItem) error {
+	err := updateFeed(catpath, item)
+	if err == nil {
+		refresh.refreshed = append(refresh.refreshed,
+			refreshedFeed{catpath, item.ID})
+	}
+	return err
+}
+
+// updateFeed asks the server to fetch the feed item, found at catpath, and
+// logs the request.
+func updateFeed(catpath string, item *ttrss.FeedTreeItem) error {
+	err := tt.UpdateFeed(item.ID)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	logChange(ChangelogEntry{
+		Op: "refresh", Path: catpath, ID: item.ID, Result: result})
+	return err
+}
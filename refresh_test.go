@@ -0,0 +1,53 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import "testing"
+
+// lastUpdatedOp answers getFeeds with feed 10, updated at each time in turn,
+// in seconds since the epoch, then at the last forever after.
+func lastUpdatedOp(times ...int64) stubOp {
+	return func(map[string]interface{}) interface{} {
+		updated := times[0]
+		if len(times) > 1 {
+			times = times[1:]
+		}
+		return []interface{}{map[string]interface{}{
+			"id": 10, "title": "A", "feed_url": "http://example.com/a",
+			"cat_id": 1, "last_updated": updated}}
+	}
+}
+
+func TestRefreshWait(t *testing.T) {
+	tests := []struct {
+		name       string
+		updated    stubOp
+		wantCode   int
+		wantStdout string
+		wantPolls  int
+	}{
+		{"updated", lastUpdatedOp(100, 100, 100, 200), EX_SUCCESS,
+			"updated /News/A\n", 4},
+		{"timed out", lastUpdatedOp(100), EX_TEMPFAIL, "", 0},
+	}
+	for _, test := range tests {
+		stub := newStubServer(t, map[string]stubOp{
+			"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "A"))),
+			"getFeeds":    test.updated,
+			"updateFeed": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{"status": "OK"}
+			},
+		})
+
+		stdout, stderr, code := runTool(t, stub, "", "refresh", "--wait",
+			"--interval", "10ms", "--wait-timeout", "500ms", "/News/A")
+		polls := len(stub.called("getFeeds"))
+		if code != test.wantCode || stdout != test.wantStdout ||
+			(test.wantPolls != 0 && polls != test.wantPolls) {
+			t.Errorf("%s: got exit %d, stdout %q, stderr %q, %d getFeeds "+
+				"calls; want exit %d, stdout %q, %d calls", test.name, code,
+				stdout, stderr, polls, test.wantCode, test.wantStdout,
+				test.wantPolls)
+		}
+	}
+}
@@ -0,0 +1,388 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ttrss"
+)
+
+type Rm struct {
+	flHelp        bool
+	flRecurse     bool
+	flInteractive bool
+	flForce       bool
+	flURL         string
+	flID          int
+	flDryRun      bool
+	flIKnow       bool
+	flags         flag.FlagSet
+
+	// answers reads the replies to -i's prompts.
+	answers *bufio.Scanner
+
+	// bulk is set once more than one thing could be removed, when Run
+	// finishes with a count of feeds and categories removed.
+	bulk      bool
+	feedsGone int
+	catsGone  int
+}
+
+func (rm *Rm) Init() {
+	rm.flags.Init("rm", flag.PanicOnError)
+
+	rm.flags.BoolVar(&rm.flHelp, "h", false, "help")
+	rm.flags.BoolVar(&rm.flHelp, "help", false, "help")
+
+	recurseHelp := "remove categories and everything in them"
+	rm.flags.BoolVar(&rm.flRecurse, "r", false, recurseHelp)
+	rm.flags.BoolVar(&rm.flRecurse, "R", false, recurseHelp)
+
+	rm.flags.BoolVar(&rm.flInteractive, "i", false,
+		"ask before each removal")
+	rm.flags.BoolVar(&rm.flForce, "f", false,
+		"ignore catpaths that name nothing, and never ask (overrides -i)")
+
+	rm.flags.StringVar(&rm.flURL, "url", "",
+		"also unsubscribe from the feed at `URL`, wherever it is")
+	rm.flags.IntVar(&rm.flID, "id", 0,
+		"also unsubscribe from the feed with `ID`, wherever it is")
+	rm.flags.BoolVar(&rm.flDryRun, "dry-run", false,
+		"say what would be removed, but remove nothing")
+	rm.flags.BoolVar(&rm.flIKnow, "i-know", false, iKnowUsage)
+}
+
+func (rm *Rm) Flags() *flag.FlagSet {
+	return &rm.flags
+}
+
+func (rm *Rm) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "rm [-fir] [--dry-run] [--i-know] [--url URL] [--id ID] "+
+		"catpath... -- unsubscribe from feeds")
+}
+
+// Run unsubscribes from each feed named.
+// It carries on past failures, and exits with the code for the last one:
+// EX_NOINPUT if nothing is at the path, EX_DATAERR if what is there is not
+// a feed, and EX_UNAVAILABLE if the server refused.
+// With -r, categories are removed along with everything in them.
+// A catpath with wildcards removes everything it matches.
+// Declining a prompt from -i is not a failure, nor, with -f, is a catpath
+// that names nothing.
+// Given more than one thing to remove, whether by several catpaths,
+// wildcards, or -r, it finishes by saying how many feeds and categories
+// went.
+// Unless it's a --dry-run, a server whose address matches the dotfile's
+// confirm_host_pattern needs --i-know.
+func (rm *Rm) Run(args []string) {
+	rm.flags.Parse(args)
+
+	if rm.flHelp {
+		flagSetPrintUsage(rm.flags, os.Stdout, "rm")
+		exit(EX_SUCCESS)
+	}
+
+	if rm.flags.NArg() < 1 && rm.flURL == "" && rm.flID == 0 {
+		if rm.flForce {
+			exit(EX_SUCCESS)
+		}
+		flagSetPrintUsage(rm.flags, os.Stderr, "rm")
+		exit(EX_USAGE)
+	}
+
+	if !rm.flDryRun {
+		confirmHostOrExit(rm.flIKnow)
+	}
+	if rm.flForce {
+		rm.flInteractive = false
+	}
+	rm.answers = bufio.NewScanner(os.Stdin)
+	targets := rm.flags.NArg()
+	if rm.flURL != "" {
+		targets++
+	}
+	if rm.flID != 0 {
+		targets++
+	}
+	rm.bulk = rm.flRecurse || targets > 1
+
+	code := EX_SUCCESS
+	for _, catpath := range rm.flags.Args() {
+		item, err := ResolveCatPath(catpath)
+		var pathErr *PathError
+		// A catpath is a pattern only if it names nothing as it stands.
+		if errors.As(err, &pathErr) && hasGlob(catpath) {
+			if globCode := rm.removeGlob(catpath); globCode != EX_SUCCESS {
+				code = globCode
+			}
+			continue
+		}
+		if err != nil && rm.flForce && errors.As(err, &pathErr) {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "rm:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		if itemCode := rm.remove(catpath, item); itemCode != EX_SUCCESS {
+			code = itemCode
+		}
+	}
+
+	if rm.flURL != "" || rm.flID != 0 {
+		if specCode := rm.removeBySpec(); specCode != EX_SUCCESS {
+			code = specCode
+		}
+	}
+	if rm.bulk {
+		rm.printSummary()
+	}
+	exit(code)
+}
+
+// printSummary says how many feeds and categories were removed, or with
+// --dry-run, would have been.
+func (rm *Rm) printSummary() {
+	verb, unsubscribed, removed := "", "unsubscribed from", "removed"
+	if rm.flDryRun {
+		verb, unsubscribed, removed = "would ", "unsubscribe from", "remove"
+	}
+	summary := fmt.Sprintf("rm: %s%s %d %s", verb, unsubscribed,
+		rm.feedsGone, plural(rm.feedsGone, "feed", "feeds"))
+	if rm.flRecurse {
+		summary += fmt.Sprintf(", %s %d %s", removed, rm.catsGone,
+			plural(rm.catsGone, "category", "categories"))
+	}
+	infof("%s", summary)
+}
+
+// removeGlob removes everything matching pattern, and returns the exit code
+// for how that went.
+func (rm *Rm) removeGlob(pattern string) (code int) {
+	matches, err := GlobCatPath(pattern)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rm:", err)
+		return EX_UNAVAILABLE
+	}
+	if len(matches) == 0 && !rm.flForce {
+		fmt.Fprintf(os.Stderr, "rm: no match: %q\n", pattern)
+		return EX_NOINPUT
+	}
+
+	rm.bulk = true
+	for _, match := range matches {
+		itemCode := rm.remove(match.Path, match.Item)
+		if itemCode != EX_SUCCESS {
+			code = itemCode
+		}
+	}
+	return
+}
+
+// remove removes item, found at catpath, and returns the exit code for how
+// that went.
+func (rm *Rm) remove(catpath string, item *ttrss.FeedTreeItem) int {
+	if rm.flRecurse && item.Type == ttrss.Category {
+		if item.IsRoot() || item.IsVirtual() {
+			fmt.Fprintf(os.Stderr,
+				"rm: refusing to remove %q: not yours to remove\n", catpath)
+			return EX_DATAERR
+		}
+		if _, ok := rm.removeCategoryTree(catpath, item); !ok {
+			return EX_UNAVAILABLE
+		}
+		return EX_SUCCESS
+	}
+
+	if item.Type == ttrss.Category && !item.IsVirtual() {
+		fmt.Fprintf(os.Stderr,
+			"rm: not a feed: %q is a category (use -r)\n", catpath)
+		return EX_DATAERR
+	}
+	if item.Type != ttrss.Feed || item.IsVirtual() {
+		fmt.Fprintf(os.Stderr, "rm: not a feed: %q\n", catpath)
+		return EX_DATAERR
+	}
+
+	if !rm.confirm("unsubscribe from feed %s?", catpath) {
+		return EX_SUCCESS
+	}
+	if rm.flDryRun {
+		fmt.Printf("would remove %s\n", display(catpath))
+		rm.feedsGone++
+		return EX_SUCCESS
+	}
+	if err := unsubscribe("rm", catpath, item); err != nil {
+		fmt.Fprintln(os.Stderr, "rm:", err)
+		return EX_UNAVAILABLE
+	}
+	rm.feedsGone++
+	return EX_SUCCESS
+}
+
+// removeBySpec removes the feeds given by --url and --id, searching the
+// whole tree for them, and returns the exit code for how that went.
+func (rm *Rm) removeBySpec() (code int) {
+	tree, err := tt.GetFeedTree(true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "rm:", err)
+		return EX_UNAVAILABLE
+	}
+	index := tree.Index()
+
+	var ids []int
+	if rm.flID != 0 {
+		ids = append(ids, rm.flID)
+	}
+	if rm.flURL != "" {
+		// The tree doesn't know feed URLs; getFeeds does.
+		feeds, err := tt.GetFeeds(
+			ttrss.CATEGORY_FEEDS_NOT_VIRTUAL, false, false)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "rm:", err)
+			return EX_UNAVAILABLE
+		}
+		found := false
+		for _, feed := range feeds {
+			if feed.FeedURL == rm.flURL {
+				ids = append(ids, feed.ID)
+				found = true
+			}
+		}
+		if !found && !rm.flForce {
+			fmt.Fprintf(os.Stderr, "rm: not subscribed to %q\n", rm.flURL)
+			code = EX_NOINPUT
+		}
+	}
+
+	for _, id := range ids {
+		item := index.Lookup(ttrss.Feed, id)
+		if item == nil {
+			if !rm.flForce {
+				fmt.Fprintf(os.Stderr, "rm: no feed with ID %d\n", id)
+				code = EX_NOINPUT
+			}
+			continue
+		}
+		catpath := index.Path(ttrss.Feed, id)
+		if itemCode := rm.remove(catpath, item); itemCode != EX_SUCCESS {
+			code = itemCode
+		}
+	}
+	return
+}
+
+// confirm asks the user whether to go ahead, if -i asked for that.
+// There's nothing to ask in a dry run.
+func (rm *Rm) confirm(format, catpath string) bool {
+	if !rm.flInteractive || rm.flDryRun {
+		return true
+	}
+	prompt := "rm: " + fmt.Sprintf(format, display(catpath))
+	return confirm(rm.answers, os.Stderr, prompt)
+}
+
+// unsubscribe unsubscribes from feed, found at catpath, and logs the change
+// as made by op.
+func unsubscribe(op, catpath string, feed *ttrss.FeedTreeItem) error {
+	err := tt.Unsubscribe(feed.ID)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	logChange(ChangelogEntry{
+		Op: op, Path: catpath, ID: feed.ID, Result: result})
+	return err
+}
+
+// removeCategory removes the empty category cat, found at catpath, and logs
+// the change as made by op.
+func removeCategory(op, catpath string, cat *ttrss.FeedTreeItem) error {
+	err := tt.RemoveCategory(cat.ID)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	logChange(ChangelogEntry{
+		Op: op, Path: catpath, ID: cat.ID, Result: result})
+	return err
+}
+
+// removeCategoryTree unsubscribes from every feed in cat, found at catpath,
+// then removes its categories, deepest first, reporting each removal.
+// A category that can't be emptied is left in place, but removal carries on
+// elsewhere. removed reports whether cat is gone, and ok whether everything
+// the user didn't decline went.
+// Uncategorized is emptied but never removed: the server needs it.
+func (rm *Rm) removeCategoryTree(catpath string, cat *ttrss.FeedTreeItem) (
+	removed, ok bool) {
+	ok = true
+	emptied := true
+	for i := range cat.Items {
+		child := &cat.Items[i]
+		// Not path.Join: that would clean away a category named "..".
+		childPath := asCategoryPath(catpath) +
+			ttrss.EscapePathComponent(child.Name)
+		if child.Type == ttrss.Category {
+			childRemoved, childOK := rm.removeCategoryTree(childPath, child)
+			emptied = emptied && childRemoved
+			ok = ok && childOK
+			continue
+		}
+
+		if !rm.confirm("unsubscribe from feed %s?", childPath) {
+			emptied = false
+			continue
+		}
+		if rm.flDryRun {
+			fmt.Printf("would remove %s\n", display(childPath))
+			rm.feedsGone++
+			continue
+		}
+		if err := unsubscribe("rm", childPath, child); err != nil {
+			fmt.Fprintf(os.Stderr, "rm: %s: %v\n", childPath, err)
+			emptied = false
+			ok = false
+			continue
+		}
+		rm.feedsGone++
+		fmt.Printf("removed %s\n", display(childPath))
+	}
+
+	if !emptied || cat.ID == ttrss.CATEGORY_UNCATEGORIZED {
+		return
+	}
+	if !rm.confirm("remove category %s?", catpath+"/") {
+		return
+	}
+	if rm.flDryRun {
+		fmt.Printf("would remove %s/\n", display(catpath))
+		rm.catsGone++
+		return true, ok
+	}
+
+	if err := removeCategory("rm", catpath, cat); err != nil {
+		fmt.Fprintf(os.Stderr, "rm: %s: %v\n", catpath, err)
+		return false, false
+	}
+	rm.catsGone++
+	fmt.Printf("removed %s/\n", display(catpath))
+	return true, ok
+}
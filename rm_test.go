@@ -0,0 +1,57 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import "testing"
+
+func TestRmRecursiveKeepsDotNames(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News",
+			catItem(3, "..", feedItem(20, "x")),
+			catItem(4, "."))),
+	})
+
+	stdout, stderr, code := runTool(t, stub, "", "rm", "-r", "--dry-run",
+		"/News")
+	want := "would remove /News/../x\n" +
+		"would remove /News/../\n" +
+		"would remove /News/./\n" +
+		"would remove /News/\n"
+	if code != EX_SUCCESS || stdout != want {
+		t.Errorf("rm -r --dry-run /News: got exit %d, stdout %q, "+
+			"stderr %q; want %q", code, stdout, stderr, want)
+	}
+}
+
+func TestRmSummary(t *testing.T) {
+	okOp := func(map[string]interface{}) interface{} {
+		return map[string]interface{}{"status": "OK"}
+	}
+	tree := treeOp(catItem(1, "News", feedItem(10, "Go"), feedItem(11, "Gum"),
+		catItem(2, "Old", feedItem(12, "Gone"))))
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"/News/Go"}, ""},
+		{[]string{"/News/G*"}, "rm: unsubscribed from 2 feeds\n"},
+		{[]string{"/News/Go", "/News/Gum"}, "rm: unsubscribed from 2 feeds\n"},
+		{[]string{"-r", "/News/Old"},
+			"rm: unsubscribed from 1 feed, removed 1 category\n"},
+		{[]string{"-r", "--dry-run", "/News"},
+			"rm: would unsubscribe from 3 feeds, remove 2 categories\n"},
+	}
+	for _, test := range tests {
+		stub := newStubServer(t, map[string]stubOp{
+			"getFeedTree":     tree,
+			"unsubscribeFeed": okOp,
+			"removeCategory":  okOp,
+		})
+		args := append([]string{"rm"}, test.args...)
+		_, stderr, code := runTool(t, stub, "", args...)
+		if code != EX_SUCCESS || stderr != test.want {
+			t.Errorf("%q: got exit %d, stderr %q; want %q", args, code,
+				stderr, test.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ttrss"
+)
+
+type Rmdir struct {
+	flHelp           bool
+	flIgnoreNonEmpty bool
+	flIKnow          bool
+	flags            flag.FlagSet
+}
+
+func (rmdir *Rmdir) Init() {
+	rmdir.flags.Init("rmdir", flag.PanicOnError)
+
+	rmdir.flags.BoolVar(&rmdir.flHelp, "h", false, "help")
+	rmdir.flags.BoolVar(&rmdir.flHelp, "help", false, "help")
+
+	rmdir.flags.BoolVar(&rmdir.flIgnoreNonEmpty, "ignore-non-empty", false,
+		"quietly leave categories that aren't empty")
+	rmdir.flags.BoolVar(&rmdir.flIKnow, "i-know", false, iKnowUsage)
+}
+
+func (rmdir *Rmdir) Flags() *flag.FlagSet {
+	return &rmdir.flags
+}
+
+func (rmdir *Rmdir) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "rmdir [--ignore-non-empty] [--i-know] catpath... "+
+		"-- remove empty categories")
+}
+
+// Run removes each category named, so long as it's empty.
+// It carries on past failures, and exits with the code for the last one:
+// EX_NOINPUT if there's no such category, EX_DATAERR if it isn't empty or
+// isn't the user's to remove, and EX_UNAVAILABLE if the server refused.
+// A server whose address matches the dotfile's confirm_host_pattern needs
+// --i-know.
+func (rmdir *Rmdir) Run(args []string) {
+	rmdir.flags.Parse(args)
+
+	if rmdir.flHelp {
+		flagSetPrintUsage(rmdir.flags, os.Stdout, "rmdir")
+		exit(EX_SUCCESS)
+	}
+
+	if rmdir.flags.NArg() < 1 {
+		flagSetPrintUsage(rmdir.flags, os.Stderr, "rmdir")
+		exit(EX_USAGE)
+	}
+	confirmHostOrExit(rmdir.flIKnow)
+
+	code := EX_SUCCESS
+	for _, catpath := range rmdir.flags.Args() {
+		// Only a category will do, so there's no sense matching feeds.
+		cat, err := ResolveCatPath(asCategoryPath(catpath))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "rmdir:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		if cat.IsRoot() || cat.IsVirtual() ||
+			cat.ID == ttrss.CATEGORY_UNCATEGORIZED {
+			fmt.Fprintf(os.Stderr,
+				"rmdir: refusing to remove %q: not yours to remove\n",
+				catpath)
+			code = EX_DATAERR
+			continue
+		}
+
+		if len(cat.Items) > 0 {
+			if !rmdir.flIgnoreNonEmpty {
+				fmt.Fprintf(os.Stderr, "rmdir: not empty: %q holds %d items\n",
+					catpath, len(cat.Items))
+				code = EX_DATAERR
+			}
+			continue
+		}
+
+		if err := removeCategory("rmdir", catpath, cat); err != nil {
+			fmt.Fprintf(os.Stderr, "rmdir: %s: %v\n", catpath, err)
+			code = EX_UNAVAILABLE
+		}
+	}
+	exit(code)
+}
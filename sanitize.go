@@ -0,0 +1,57 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// display makes text from the server safe to print to a terminal, unless
+// --raw-names: control characters are escaped Go-style, like \x1b, and
+// invalid UTF-8 becomes U+FFFD.
+// Feed titles come from whoever runs the feed, and an escape sequence in one
+// could otherwise rewrite the screen.
+func display(text string) string {
+	if flRawNames {
+		return text
+	}
+	return escapeControls(text)
+}
+
+// escapeControls does display's work, whatever --raw-names says.
+func escapeControls(text string) string {
+	var b strings.Builder
+	for i, w := 0, 0; i < len(text); i += w {
+		r, width := utf8.DecodeRuneInString(text[i:])
+		w = width
+		switch {
+		case r == utf8.RuneError && width <= 1:
+			b.WriteRune(unicode.ReplacementChar)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case unicode.IsControl(r) && r < 0x80:
+			fmt.Fprintf(&b, `\x%02x`, r)
+		case unicode.IsControl(r):
+			fmt.Fprintf(&b, `\u%04x`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// displayLines is display for multi-line text: the newlines are kept.
+func displayLines(text string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = display(line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,76 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import "testing"
+
+func TestEscapeControls(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"plain", "plain"},
+		{"café ☕", "café ☕"},
+		{"\x1b[2Jgotcha", `\x1b[2Jgotcha`},
+		{"bell\a", `bell\x07`},
+		{"tab\tnew\nline\r", `tab\tnew\nline\r`},
+		{"del\x7f", `del\x7f`},
+		{"c1\u009b", `c1\u009b`},
+		{"bad\xffbyte", "bad�byte"},
+		{"cut \xe2\x98", "cut ��"},
+		{"� itself", "� itself"},
+	}
+	for _, test := range tests {
+		if got := escapeControls(test.text); got != test.want {
+			t.Errorf("escapeControls(%q) = %q, want %q", test.text, got,
+				test.want)
+		}
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	defer func(saved bool) { flRawNames = saved }(flRawNames)
+	tests := []struct {
+		raw       bool
+		text      string
+		want      string
+		wantLines string
+	}{
+		{false, "a\x1b\nb\xff", `a\x1b\nb` + "�",
+			"a\\x1b\nb�"},
+		{true, "a\x1b\nb\xff", "a\x1b\nb\xff", "a\x1b\nb\xff"},
+	}
+	for _, test := range tests {
+		flRawNames = test.raw
+		if got := display(test.text); got != test.want {
+			t.Errorf("display(%q) with --raw-names %v = %q, want %q",
+				test.text, test.raw, got, test.want)
+		}
+		if got := displayLines(test.text); got != test.wantLines {
+			t.Errorf("displayLines(%q) with --raw-names %v = %q, want %q",
+				test.text, test.raw, got, test.wantLines)
+		}
+	}
+}
+
+func TestLsEscapesNames(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News",
+			feedItem(11, "Bell\a"), feedItem(10, "Evil\x1b[2J"))),
+	})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"ls", "/News"}, `Bell\x07` + "\n" + `Evil\x1b[2J` + "\n"},
+		{[]string{"--raw-names", "ls", "/News"},
+			"Bell\a\nEvil\x1b[2J\n"},
+	}
+	for _, test := range tests {
+		stdout, stderr, code := runTool(t, stub, "", test.args...)
+		if code != EX_SUCCESS || stdout != test.want {
+			t.Errorf("%q: got exit %d, stdout %q, stderr %q; want %q",
+				test.args, code, stdout, stderr, test.want)
+		}
+	}
+}
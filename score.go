@@ -0,0 +1,207 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"ttrss"
+)
+
+type Score struct {
+	flHelp bool
+	flFeed string
+	flags  flag.FlagSet
+}
+
+func (score *Score) Init() {
+	score.flags.Init("score", flag.PanicOnError)
+
+	score.flags.BoolVar(&score.flHelp, "h", false, "help")
+	score.flags.BoolVar(&score.flHelp, "help", false, "help")
+
+	score.flags.StringVar(&score.flFeed, "feed", "",
+		"score every article in the feed or category at `catpath` too")
+}
+
+func (score *Score) Flags() *flag.FlagSet {
+	return &score.flags
+}
+
+func (score *Score) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "score show article_id -- print an article's score")
+	fmt.Fprintln(w, "score [--feed catpath] set|add N article_id... "+
+		"-- set articles' scores, or raise them by N")
+}
+
+// Run prints an article's score, or sets or adjusts the scores of articles,
+// as its first argument says. Article IDs are as for star, "-" and all.
+// With --feed, every article the server has in a feed or category is scored
+// too.
+// It exits EX_DATAERR if an ID is malformed, EX_NOINPUT if an article or
+// --feed names nothing, and EX_UNAVAILABLE if the server refused.
+// Servers that predate scoring through the API would change nothing, and
+// say nothing of it either, so for them, setting scores gives up with
+// EX_UNAVAILABLE before trying.
+func (score *Score) Run(args []string) {
+	score.flags.Parse(args)
+
+	if score.flHelp {
+		flagSetPrintUsage(score.flags, os.Stdout, "score")
+		exit(EX_SUCCESS)
+	}
+
+	argc := score.flags.NArg()
+	switch score.flags.Arg(0) {
+	case "show":
+		if argc == 2 && score.flFeed == "" {
+			exit(showScore(score.flags.Arg(1)))
+		}
+	case "set", "add":
+		if argc >= 3 || argc == 2 && score.flFeed != "" {
+			n, err := strconv.Atoi(score.flags.Arg(1))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "score: not a score: %q\n",
+					score.flags.Arg(1))
+				exit(EX_USAGE)
+			}
+			add := score.flags.Arg(0) == "add"
+			exit(score.apply(n, add, score.flags.Args()[2:]))
+		}
+	}
+	flagSetPrintUsage(score.flags, os.Stderr, "score")
+	exit(EX_USAGE)
+}
+
+// showScore prints the score of the article with ID arg, and returns the
+// exit code for how that went.
+func showScore(arg string) int {
+	ids, err := parseArticleIDs([]string{arg})
+	if err != nil || len(ids) != 1 {
+		if err == nil {
+			err = fmt.Errorf("not one article ID: %q", arg)
+		}
+		fmt.Fprintln(os.Stderr, "score:", err)
+		return EX_DATAERR
+	}
+
+	articles, err := tt.GetArticles(ids[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "score:", err)
+		return EX_UNAVAILABLE
+	}
+	if len(articles) == 0 {
+		fmt.Fprintf(os.Stderr, "score: no article %d\n", ids[0])
+		return EX_NOINPUT
+	}
+	fmt.Println(articles[0].Score)
+	return EX_SUCCESS
+}
+
+// apply sets the score of each article in args, and with --feed, in that
+// feed, to n, or if add is set, adds n to it. It returns the exit code for
+// how that went.
+func (score *Score) apply(n int, add bool, args []string) int {
+	ids, err := parseArticleIDs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "score:", err)
+		return EX_DATAERR
+	}
+
+	level, err := tt.GetApiLevel()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "score:", err)
+		return EX_UNAVAILABLE
+	}
+	if level < ttrss.API_LEVEL_SCORE {
+		fmt.Fprintf(os.Stderr, "score: server API level %d is too old to "+
+			"set scores (need %d)\n", level, ttrss.API_LEVEL_SCORE)
+		return EX_UNAVAILABLE
+	}
+
+	// The server sets one score at a time, so group the articles by theirs.
+	scored := make(map[int][]int)
+	if len(ids) > 0 {
+		if !add {
+			scored[n] = ids
+		} else {
+			// Adding needs the scores as they are.
+			articles, err := tt.GetArticles(ids...)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "score:", err)
+				return EX_UNAVAILABLE
+			}
+			found := make(map[int]bool)
+			for _, a := range articles {
+				found[a.ID] = true
+				scored[a.Score+n] = append(scored[a.Score+n], a.ID)
+			}
+			for _, id := range ids {
+				if !found[id] {
+					fmt.Fprintf(os.Stderr, "score: no article %d\n", id)
+					return EX_NOINPUT
+				}
+			}
+		}
+	}
+	if score.flFeed != "" {
+		item, err := ResolveCatPath(score.flFeed)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "score:", err)
+			printCandidates(err)
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				return EX_NOINPUT
+			}
+			return EX_DATAERR
+		}
+		err = eachHeadline(headlinesRequestFor(item), func(h ttrss.Headline) {
+			to := n
+			if add {
+				to += h.Score
+			}
+			scored[to] = append(scored[to], h.ID)
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "score: %s: %v\n", score.flFeed, err)
+			return EX_UNAVAILABLE
+		}
+	}
+
+	scores := make([]int, 0, len(scored))
+	for to := range scored {
+		scores = append(scores, to)
+	}
+	sort.Ints(scores)
+	for _, to := range scores {
+		if err := setScore(scored[to], to); err != nil {
+			fmt.Fprintln(os.Stderr, "score:", err)
+			return EX_UNAVAILABLE
+		}
+	}
+	return EX_SUCCESS
+}
+
+// setScore sets the score of the articles with ids to to, and logs the
+// change to each.
+func setScore(ids []int, to int) error {
+	updated, err := tt.UpdateArticle(ids, ttrss.FIELD_SCORE,
+		ttrss.UPDATE_TRUE, strconv.Itoa(to))
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected += updated
+	}
+	for _, id := range ids {
+		logChange(ChangelogEntry{Op: "score", ID: id, To: strconv.Itoa(to),
+			Result: result})
+	}
+	return err
+}
@@ -0,0 +1,44 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"testing"
+	"ttrss"
+)
+
+func TestScoreSetNeedsScoring(t *testing.T) {
+	tests := []struct {
+		level int
+		want  int
+	}{
+		{ttrss.API_LEVEL_SCORE, EX_SUCCESS},
+		{ttrss.API_LEVEL_SCORE - 1, EX_UNAVAILABLE},
+	}
+	for _, test := range tests {
+		stub := newStubServer(t, map[string]stubOp{
+			"getApiLevel": apiLevelOp(test.level),
+			"updateArticle": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{"status": "OK", "updated": 1}
+			},
+		})
+
+		_, stderr, code := runTool(t, stub, "", "score", "set", "5", "100")
+		calls := stub.called("updateArticle")
+		wantCalls := 0
+		if test.want == EX_SUCCESS {
+			wantCalls = 1
+		}
+		if code != test.want || len(calls) != wantCalls {
+			t.Errorf("score set at API level %d: got exit %d, stderr %q, "+
+				"updateArticle calls %+v; want exit %d and %d calls",
+				test.level, code, stderr, calls, test.want, wantCalls)
+			continue
+		}
+		if wantCalls > 0 && (calls[0].Req["field"] != 4.0 ||
+			calls[0].Req["data"] != "5") {
+			t.Errorf("score set: got updateArticle call %+v, "+
+				"want field 4 and data 5", calls[0])
+		}
+	}
+}
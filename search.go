@@ -0,0 +1,110 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+)
+
+type Search struct {
+	flHelp   bool
+	flCount  int
+	flFormat string
+	flags    flag.FlagSet
+}
+
+func (search *Search) Init() {
+	search.flags.Init("search", flag.PanicOnError)
+
+	search.flags.BoolVar(&search.flHelp, "h", false, "help")
+	search.flags.BoolVar(&search.flHelp, "help", false, "help")
+
+	search.flags.IntVar(&search.flCount, "n", 0,
+		"print at most `N` articles (default: as many as the server sends)")
+	search.flags.StringVar(&search.flFormat, "format", "",
+		"print each article using the Go text/template `TEMPLATE`")
+}
+
+func (search *Search) Flags() *flag.FlagSet {
+	return &search.flags
+}
+
+func (search *Search) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "search [-n N] [--format TEMPLATE] query [catpath] "+
+		"-- search articles using the server's search")
+}
+
+// Run prints the articles the server finds for query within the feed or
+// category at catpath (by default, everywhere), newest first, as tail does.
+// With --format, each article is printed as the template says, as for cat.
+// As with grep, it exits EX_NOMATCH if nothing was found.
+func (search *Search) Run(args []string) {
+	search.flags.Parse(args)
+
+	if search.flHelp {
+		flagSetPrintUsage(search.flags, os.Stdout, "search")
+		exit(EX_SUCCESS)
+	}
+
+	argc := search.flags.NArg()
+	if argc < 1 || argc > 2 || search.flCount < 0 {
+		flagSetPrintUsage(search.flags, os.Stderr, "search")
+		exit(EX_USAGE)
+	}
+
+	var format *template.Template
+	if search.flFormat != "" {
+		var err error
+		format, err = parseFormat(search.flFormat, HeadlineView{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "search: bad --format:", err)
+			exit(EX_USAGE)
+		}
+	}
+
+	query := search.flags.Arg(0)
+	catpath := "/"
+	if argc > 1 {
+		catpath = search.flags.Arg(1)
+	}
+	item, err := ResolveCatPath(catpath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "search:", err)
+		printCandidates(err)
+		var pathErr *PathError
+		if errors.As(err, &pathErr) {
+			exit(EX_NOINPUT)
+		}
+		exit(EX_DATAERR)
+	}
+
+	req := headlinesRequestFor(item)
+	req.Search = query
+	req.Limit = search.flCount
+	found, err := tt.GetHeadlines(req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "search:", err)
+		exit(EX_UNAVAILABLE)
+	}
+
+	for _, h := range found {
+		if format == nil {
+			printHeadline(os.Stdout, h)
+			continue
+		}
+		if err := printFormatted(os.Stdout, format,
+			viewHeadline(h)); err != nil {
+			fmt.Fprintln(os.Stderr, "search:", err)
+			exit(EX_DATAERR)
+		}
+	}
+	if len(found) == 0 {
+		exit(EX_NOMATCH)
+	}
+	exit(EX_SUCCESS)
+}
@@ -0,0 +1,109 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import "fmt"
+
+// The stock API can't change categories at all, nor where a feed is filed
+// or what it's called. The calls in this file use ops a server plugin can
+// provide (see API.md); without one, they fail with an *UnsupportedOpError.
+
+// RemoveCategory removes the empty category with the given ID.
+func (tt *Client) RemoveCategory(categoryID int) (err error) {
+	removeMap := map[string]interface{}{
+		"category_id": categoryID,
+	}
+	resp, err := tt.Call("removeCategory", removeMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("removeCategory: %w", resp.Error)
+	}
+	return
+}
+
+// AddCategory creates a category titled title within the category with ID
+// parentID, or at the top level if parentID is CATEGORY_UNCATEGORIZED, and
+// returns the new category's ID.
+func (tt *Client) AddCategory(title string, parentID int) (
+	categoryID int, err error) {
+	addMap := map[string]interface{}{
+		"caption": title,
+	}
+	if parentID != CATEGORY_UNCATEGORIZED {
+		addMap["parent_id"] = parentID
+	}
+	resp, err := tt.Call("addCategory", addMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("addCategory: %w", resp.Error)
+		return
+	}
+
+	id, ok := resp.Content["category_id"].(float64)
+	if !ok {
+		err = fmt.Errorf("addCategory: no category ID: have instead %#v",
+			resp.Content)
+		return
+	}
+	categoryID = int(id)
+	return
+}
+
+// MoveFeed files the feed with ID feedID under the category with ID
+// categoryID, which may be CATEGORY_UNCATEGORIZED.
+func (tt *Client) MoveFeed(feedID, categoryID int) (err error) {
+	moveMap := map[string]interface{}{
+		"feed_id":     feedID,
+		"category_id": categoryID,
+	}
+	resp, err := tt.Call("moveFeed", moveMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("moveFeed: %w", resp.Error)
+	}
+	return
+}
+
+// RenameCategory retitles the category with ID categoryID, leaving what's in
+// it alone.
+func (tt *Client) RenameCategory(categoryID int, title string) (err error) {
+	renameMap := map[string]interface{}{
+		"category_id": categoryID,
+		"caption":     title,
+	}
+	resp, err := tt.Call("renameCategory", renameMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("renameCategory: %w", resp.Error)
+	}
+	return
+}
+
+// RenameFeed sets the title the feed with ID feedID is shown under.
+func (tt *Client) RenameFeed(feedID int, title string) (err error) {
+	renameMap := map[string]interface{}{
+		"feed_id": feedID,
+		"title":   title,
+	}
+	resp, err := tt.Call("renameFeed", renameMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("renameFeed: %w", resp.Error)
+	}
+	return
+}
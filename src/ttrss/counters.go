@@ -0,0 +1,78 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Counters holds the unread counts reported by getCounters.
+type Counters struct {
+	// Feeds maps feed IDs, including virtual and label feeds, to their
+	// unread counts.
+	Feeds map[int]int
+
+	// Categories maps category IDs to the server's own unread counts.
+	// Whether these include nested categories varies with the server, so
+	// summing Feeds is safer.
+	Categories map[int]int
+
+	// GlobalUnread is the total unread count, as the web UI shows it.
+	GlobalUnread int
+}
+
+// GetCounters fetches the unread counts of every feed and category.
+func (tt *Client) GetCounters() (counters Counters, err error) {
+	getMap := map[string]interface{}{
+		// feeds, labels, categories: everything but tags
+		"output_mode": "flc",
+	}
+	resp, err := tt.Call("getCounters", getMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("getCounters: API error: %s", resp.Error)
+		return
+	}
+
+	// Most IDs are numbers, but a few are names, like "global-unread".
+	var items []struct {
+		ID      json.RawMessage
+		Kind    string
+		Counter json.Number
+	}
+	err = json.Unmarshal(resp.RawContent, &items)
+	if err != nil {
+		err = fmt.Errorf("getCounters: content is not a list of counters: %v",
+			err)
+		return
+	}
+
+	counters.Feeds = make(map[int]int)
+	counters.Categories = make(map[int]int)
+	for _, item := range items {
+		counter, convErr := item.Counter.Int64()
+		if convErr != nil {
+			continue
+		}
+
+		var id int
+		if json.Unmarshal(item.ID, &id) != nil {
+			var name string
+			if json.Unmarshal(item.ID, &name) == nil &&
+				name == "global-unread" {
+				counters.GlobalUnread = int(counter)
+			}
+			continue
+		}
+		if item.Kind == "cat" {
+			counters.Categories[id] = int(counter)
+		} else {
+			counters.Feeds[id] = int(counter)
+		}
+	}
+	return
+}
@@ -0,0 +1,171 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FeedInfo is a subscription as described by getFeeds, which tells far more
+// about a feed than the feed tree does.
+type FeedInfo struct {
+	ID          int
+	Title       string
+	FeedURL     string
+	CategoryID  int
+	Unread      int
+	HasIcon     bool
+	LastUpdated time.Time
+	OrderID     int
+}
+
+// UnmarshalJSON decodes a feed as sent by the API.
+// As with headlines, IDs may arrive as numbers or as strings.
+func (feed *FeedInfo) UnmarshalJSON(data []byte) error {
+	var wire struct {
+		ID          json.Number
+		Title       string
+		FeedURL     string      `json:"feed_url"`
+		CatID       json.Number `json:"cat_id"`
+		Unread      json.Number
+		HasIcon     bool        `json:"has_icon"`
+		LastUpdated int64       `json:"last_updated"`
+		OrderID     json.Number `json:"order_id"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	ints := []struct {
+		name string
+		from json.Number
+		to   *int
+	}{
+		{"id", wire.ID, &feed.ID},
+		{"cat_id", wire.CatID, &feed.CategoryID},
+		{"unread", wire.Unread, &feed.Unread},
+		{"order_id", wire.OrderID, &feed.OrderID},
+	}
+	for _, field := range ints {
+		if field.from == "" {
+			*field.to = 0
+			continue
+		}
+		n, err := field.from.Int64()
+		if err != nil {
+			return fmt.Errorf("feed has bad %s %q: %v",
+				field.name, field.from, err)
+		}
+		*field.to = int(n)
+	}
+
+	feed.Title = wire.Title
+	feed.FeedURL = wire.FeedURL
+	feed.HasIcon = wire.HasIcon
+	feed.LastUpdated = time.Time{}
+	if wire.LastUpdated != 0 {
+		feed.LastUpdated = time.Unix(wire.LastUpdated, 0)
+	}
+	return nil
+}
+
+// GetFeeds lists the feeds in the category with ID categoryID.
+// Use CATEGORY_FEEDS_NOT_VIRTUAL to list every real feed, wherever it's
+// filed. Subcategories are not included, and their feeds only if
+// includeNested.
+func (tt *Client) GetFeeds(categoryID int, unreadOnly bool, includeNested bool) (feeds []FeedInfo, err error) {
+	getMap := map[string]interface{}{
+		"cat_id":         categoryID,
+		"unread_only":    unreadOnly,
+		"include_nested": includeNested,
+	}
+	resp, err := tt.Call("getFeeds", getMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("getFeeds: API error: %s", resp.Error)
+		return
+	}
+
+	// With include_nested, categories come back mixed in with the feeds.
+	var items []json.RawMessage
+	err = json.Unmarshal(resp.RawContent, &items)
+	if err != nil {
+		err = fmt.Errorf("getFeeds: content is not a list: %v", err)
+		return
+	}
+	for _, raw := range items {
+		var kind struct {
+			IsCat bool `json:"is_cat"`
+		}
+		if json.Unmarshal(raw, &kind) == nil && kind.IsCat {
+			continue
+		}
+
+		var feed FeedInfo
+		err = json.Unmarshal(raw, &feed)
+		if err != nil {
+			err = fmt.Errorf("getFeeds: malformed feed: %v", err)
+			return
+		}
+		feeds = append(feeds, feed)
+	}
+	return
+}
+
+// UpdateFeed asks the server to fetch the feed with ID feedID now, rather
+// than waiting for its update daemon to get to it. The server may do so in
+// the background, so the feed's articles and LastUpdated may not change
+// until some time after this returns.
+func (tt *Client) UpdateFeed(feedID int) (err error) {
+	updateMap := map[string]interface{}{
+		"feed_id": feedID,
+	}
+	resp, err := tt.Call("updateFeed", updateMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("updateFeed: %w", resp.Error)
+	}
+	return
+}
+
+// The stock API can't purge a feed's old articles; only the update daemon
+// does, on the schedule set in each feed's preferences. PurgeFeed uses an op
+// a server plugin can provide (see API.md); without one, it fails with an
+// *UnsupportedOpError.
+
+// PurgeFeed deletes the articles in the feed with ID feedID that are older
+// than days days, as the update daemon would, and returns how many it
+// deleted. Starred articles are kept, as are unread ones unless the user has
+// asked the server to purge those too.
+func (tt *Client) PurgeFeed(feedID, days int) (purged int, err error) {
+	purgeMap := map[string]interface{}{
+		"feed_id": feedID,
+		"days":    days,
+	}
+	resp, err := tt.Call("purgeFeed", purgeMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("purgeFeed: %w", resp.Error)
+		return
+	}
+
+	count, ok := resp.Content["purged"].(float64)
+	if !ok {
+		err = fmt.Errorf("purgeFeed: no count purged: have instead %#v",
+			resp.Content)
+		return
+	}
+	purged = int(count)
+	return
+}
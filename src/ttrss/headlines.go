@@ -0,0 +1,317 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// View modes accepted by GetHeadlines.
+const (
+	VIEW_ALL_ARTICLES = "all_articles"
+	VIEW_UNREAD       = "unread"
+	VIEW_ADAPTIVE     = "adaptive"
+	VIEW_MARKED       = "marked"
+	VIEW_UPDATED      = "updated"
+)
+
+// Orderings accepted by GetHeadlines. The server default is newest first.
+const (
+	ORDER_DATE_REVERSE = "date_reverse"
+	ORDER_FEED_DATES   = "feed_dates"
+)
+
+// Catchup modes accepted by CatchupFeed: how old an article must be to be
+// marked read. The server default is CATCHUP_ALL.
+const (
+	CATCHUP_ALL   = "all"
+	CATCHUP_1DAY  = "1day"
+	CATCHUP_1WEEK = "1week"
+	CATCHUP_2WEEK = "2week"
+)
+
+// API_LEVEL_CATCHUP_MODE is the first API level whose servers honor
+// CatchupFeed's mode. Older ones don't refuse it; they ignore it.
+const API_LEVEL_CATCHUP_MODE = 15
+
+// ArticleField names a field of an article that UpdateArticle can change.
+type ArticleField int
+
+const (
+	FIELD_STARRED ArticleField = iota
+	FIELD_PUBLISHED
+	FIELD_UNREAD
+	FIELD_NOTE
+
+	// FIELD_SCORE is only known to servers at API_LEVEL_SCORE and up.
+	// Older ones change nothing, and don't say so.
+	FIELD_SCORE
+)
+
+// API_LEVEL_SCORE is the first API level whose servers know FIELD_SCORE.
+const API_LEVEL_SCORE = 15
+
+// UpdateMode says what UpdateArticle does to a field.
+type UpdateMode int
+
+const (
+	UPDATE_FALSE UpdateMode = iota
+	UPDATE_TRUE
+	UPDATE_TOGGLE
+)
+
+// HeadlinesRequest describes which headlines GetHeadlines should fetch.
+// The zero value of each field leaves the server's default in place.
+type HeadlinesRequest struct {
+	// FeedID is a feed ID, or a category ID if IsCat is set.
+	FeedID int
+	IsCat  bool
+
+	// Limit caps how many headlines come back. The server imposes its own
+	// cap (200 as of 1.9) regardless.
+	Limit int
+	Skip  int
+
+	// SinceID, if set, restricts the results to articles with a greater ID.
+	SinceID int
+
+	// ViewMode is one of the VIEW_* constants.
+	ViewMode string
+
+	// OrderBy is one of the ORDER_* constants.
+	OrderBy string
+
+	// Search, if set, is a query in the server's search syntax, like
+	// "unread:true @2weeks go". Only matching articles come back.
+	Search string
+
+	ShowExcerpt   bool
+	ShowContent   bool
+	IncludeNested bool
+}
+
+// Headline is an article as summarized by getHeadlines.
+type Headline struct {
+	ID        int
+	Unread    bool
+	Marked    bool
+	Published bool
+	Updated   time.Time
+	Title     string
+	Link      string
+	Author    string
+	FeedID    int
+	FeedTitle string
+
+	// Score is the article's score, which filters and readers can raise
+	// or lower. The server sorts articles with higher ones first when
+	// asked for "fresh" articles.
+	Score int
+
+	// Excerpt and Content are only present if asked for.
+	Excerpt string
+	Content string
+}
+
+// UnmarshalJSON decodes a headline as sent by the API.
+// Depending on the server version and database, IDs may arrive as numbers or
+// as strings, and Updated is a Unix timestamp.
+func (h *Headline) UnmarshalJSON(data []byte) error {
+	var wire struct {
+		ID        json.Number
+		Unread    bool
+		Marked    bool
+		Published bool
+		Updated   int64
+		Title     string
+		Link      string
+		Author    string
+		FeedID    json.Number `json:"feed_id"`
+		FeedTitle string      `json:"feed_title"`
+		Score     json.Number
+		Excerpt   string
+		Content   string
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	id, err := wire.ID.Int64()
+	if err != nil {
+		return fmt.Errorf("headline has bad id %q: %v", wire.ID, err)
+	}
+	feedID, err := wire.FeedID.Int64()
+	if wire.FeedID != "" && err != nil {
+		return fmt.Errorf("headline has bad feed_id %q: %v", wire.FeedID, err)
+	}
+	score, err := wire.Score.Int64()
+	if wire.Score != "" && err != nil {
+		return fmt.Errorf("headline has bad score %q: %v", wire.Score, err)
+	}
+
+	*h = Headline{
+		ID:        int(id),
+		Unread:    wire.Unread,
+		Marked:    wire.Marked,
+		Published: wire.Published,
+		Updated:   time.Unix(wire.Updated, 0),
+		Title:     wire.Title,
+		Link:      wire.Link,
+		Author:    wire.Author,
+		FeedID:    int(feedID),
+		FeedTitle: wire.FeedTitle,
+		Score:     int(score),
+		Excerpt:   wire.Excerpt,
+		Content:   wire.Content,
+	}
+	return nil
+}
+
+// GetHeadlines fetches the headlines selected by req.
+func (tt *Client) GetHeadlines(req HeadlinesRequest) (headlines []Headline, err error) {
+	getMap := map[string]interface{}{
+		"feed_id":        req.FeedID,
+		"is_cat":         req.IsCat,
+		"show_excerpt":   req.ShowExcerpt,
+		"show_content":   req.ShowContent,
+		"include_nested": req.IncludeNested,
+	}
+	if req.Limit > 0 {
+		getMap["limit"] = req.Limit
+	}
+	if req.Skip > 0 {
+		getMap["skip"] = req.Skip
+	}
+	if req.SinceID > 0 {
+		getMap["since_id"] = req.SinceID
+	}
+	if req.ViewMode != "" {
+		getMap["view_mode"] = req.ViewMode
+	}
+	if req.OrderBy != "" {
+		getMap["order_by"] = req.OrderBy
+	}
+	if req.Search != "" {
+		getMap["search"] = req.Search
+	}
+
+	resp, err := tt.Call("getHeadlines", getMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("getHeadlines: API error: %s", resp.Error)
+		return
+	}
+
+	err = json.Unmarshal(resp.RawContent, &headlines)
+	if err != nil {
+		err = fmt.Errorf("getHeadlines: content is not a list of headlines: %v",
+			err)
+	}
+	return
+}
+
+// GetArticles fetches the articles with the given IDs, content and all.
+// Articles come back as headlines, in no particular order; any that don't
+// exist are left out.
+func (tt *Client) GetArticles(ids ...int) (articles []Headline, err error) {
+	if len(ids) == 0 {
+		return
+	}
+
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = strconv.Itoa(id)
+	}
+	getMap := map[string]interface{}{
+		"article_id": strings.Join(idStrings, ","),
+	}
+	resp, err := tt.Call("getArticle", getMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("getArticle: API error: %s", resp.Error)
+		return
+	}
+
+	err = json.Unmarshal(resp.RawContent, &articles)
+	if err != nil {
+		err = fmt.Errorf("getArticle: content is not a list of articles: %v",
+			err)
+	}
+	return
+}
+
+// CatchupFeed marks every article in the feed with ID feedID as read, or, if
+// isCat is set, every article in the feeds directly in the category with
+// that ID. mode is one of the CATCHUP_* constants, or "" for the default.
+// Servers below API_LEVEL_CATCHUP_MODE ignore it, and catch up everything
+// regardless, so check GetApiLevel first.
+func (tt *Client) CatchupFeed(feedID int, isCat bool, mode string) (
+	err error) {
+	catchupMap := map[string]interface{}{
+		"feed_id": feedID,
+		"is_cat":  isCat,
+	}
+	if mode != "" {
+		catchupMap["mode"] = mode
+	}
+	resp, err := tt.Call("catchupFeed", catchupMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("catchupFeed: %w", resp.Error)
+	}
+	return
+}
+
+// UpdateArticle sets field on each of the articles with the given IDs, as
+// mode says. data is the new note for FIELD_NOTE, or the new score, in
+// decimal, for FIELD_SCORE; otherwise, it's ignored.
+// updated is how many articles the server says it changed. Some databases
+// count only those that weren't already as asked, so it's no way to tell
+// whether an ID exists.
+func (tt *Client) UpdateArticle(ids []int, field ArticleField,
+	mode UpdateMode, data string) (updated int, err error) {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = strconv.Itoa(id)
+	}
+	updateMap := map[string]interface{}{
+		"article_ids": strings.Join(idStrings, ","),
+		"field":       int(field),
+		"mode":        int(mode),
+	}
+	if field == FIELD_NOTE || field == FIELD_SCORE {
+		updateMap["data"] = data
+	}
+	resp, err := tt.Call("updateArticle", updateMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("updateArticle: %w", resp.Error)
+		return
+	}
+
+	var content struct {
+		Updated json.Number `json:"updated"`
+	}
+	if err = json.Unmarshal(resp.RawContent, &content); err != nil {
+		err = fmt.Errorf("updateArticle: unexpected content: %v", err)
+		return
+	}
+	count, _ := content.Updated.Int64()
+	return int(count), nil
+}
@@ -0,0 +1,68 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import "strings"
+
+// EscapePathComponent escapes the slashes in name, so that it can be used as
+// a single component of a catpath like "/News/Tech".
+// A slash preceded by a backslash does not separate components.
+func EscapePathComponent(name string) string {
+	return strings.Replace(name, "/", "\\/", -1)
+}
+
+// FeedTreeIndex finds items in a feed tree by ID without walking it.
+// Category and feed IDs overlap, so lookups need the type, too.
+// An index describes the tree as it was when indexed: re-index after
+// fetching the tree again.
+type FeedTreeIndex struct {
+	items map[indexKey]*FeedTreeItem
+	paths map[indexKey]string
+}
+
+type indexKey struct {
+	Type string
+	ID   int
+}
+
+// Index indexes the tree rooted at root, which should be the root returned
+// by GetFeedTree for Path to give full catpaths. The root itself is not
+// indexed: CAT:0 is Uncategorized.
+func (root *FeedTreeItem) Index() *FeedTreeIndex {
+	index := &FeedTreeIndex{
+		items: make(map[indexKey]*FeedTreeItem),
+		paths: make(map[indexKey]string),
+	}
+	index.add(root, "")
+	return index
+}
+
+func (index *FeedTreeIndex) add(item *FeedTreeItem, path string) {
+	// The root shares its ID with Uncategorized, so it's left out.
+	if !item.IsRoot() {
+		key := indexKey{item.Type, item.ID}
+		if _, dup := index.items[key]; dup {
+			// The server repeats some virtual feeds; keep the first.
+			return
+		}
+		index.items[key] = item
+		index.paths[key] = path
+	}
+
+	for i := range item.Items {
+		child := &item.Items[i]
+		index.add(child, path+"/"+EscapePathComponent(child.Name))
+	}
+}
+
+// Lookup returns the item of type itemType (Category or Feed) with ID id,
+// or nil if there's none.
+func (index *FeedTreeIndex) Lookup(itemType string, id int) *FeedTreeItem {
+	return index.items[indexKey{itemType, id}]
+}
+
+// Path returns the catpath of the item of type itemType with ID id, or "" if
+// there's none.
+func (index *FeedTreeIndex) Path(itemType string, id int) string {
+	return index.paths[indexKey{itemType, id}]
+}
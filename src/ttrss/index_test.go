@@ -0,0 +1,161 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// linearLookup finds the item of type itemType with ID id, and its catpath,
+// by walking the tree under item, which is at path, as Index saves doing.
+func linearLookup(item *FeedTreeItem, path, itemType string, id int) (
+	*FeedTreeItem, string) {
+	if !item.IsRoot() && item.Type == itemType && item.ID == id {
+		return item, path
+	}
+	for i := range item.Items {
+		child := &item.Items[i]
+		found, foundPath := linearLookup(child,
+			path+"/"+EscapePathComponent(child.Name), itemType, id)
+		if found != nil {
+			return found, foundPath
+		}
+	}
+	return nil, ""
+}
+
+func testTree() *FeedTreeItem {
+	return &FeedTreeItem{Name: "/", Type: Category, Items: []FeedTreeItem{
+		{ID: CATEGORY_SPECIAL, Name: "Special", Type: Category,
+			Items: []FeedTreeItem{
+				{ID: FEED_STARRED_ARTICLES, Name: "Starred", Type: Feed},
+				{ID: FEED_ALL_ARTICLES, Name: "All", Type: Feed},
+			}},
+		{ID: 1, Name: "News", Type: Category, Items: []FeedTreeItem{
+			{ID: 1, Name: "Also 1", Type: Feed},
+			{ID: 2, Name: "Tech/Science", Type: Category,
+				Items: []FeedTreeItem{
+					{ID: 10, Name: "Go Blog", Type: Feed},
+				}},
+		}},
+		{ID: CATEGORY_UNCATEGORIZED, Name: "Uncategorized", Type: Category,
+			Items: []FeedTreeItem{
+				{ID: 5, Name: "Lonely", Type: Feed},
+				// Repeated, as virtual feeds can be.
+				{ID: FEED_ALL_ARTICLES, Name: "All again", Type: Feed},
+			}},
+	}}
+}
+
+func TestIndex(t *testing.T) {
+	root := testTree()
+	index := root.Index()
+
+	tests := []struct {
+		itemType string
+		id       int
+		want     string
+	}{
+		{Category, CATEGORY_SPECIAL, "/Special"},
+		{Feed, FEED_ALL_ARTICLES, "/Special/All"},
+		{Category, 1, "/News"},
+		{Feed, 1, "/News/Also 1"},
+		{Category, 2, `/News/Tech\/Science`},
+		{Feed, 10, `/News/Tech\/Science/Go Blog`},
+		{Category, CATEGORY_UNCATEGORIZED, "/Uncategorized"},
+		{Feed, 5, "/Uncategorized/Lonely"},
+		{Feed, 99, ""},
+		{Category, 10, ""},
+	}
+	for _, test := range tests {
+		item := index.Lookup(test.itemType, test.id)
+		path := index.Path(test.itemType, test.id)
+		wantItem, wantPath := linearLookup(root, "", test.itemType, test.id)
+		if item != wantItem || path != wantPath || path != test.want {
+			t.Errorf("%s:%d: got %p at %q, want %p at %q", test.itemType,
+				test.id, item, path, wantItem, test.want)
+		}
+	}
+}
+
+// benchTree returns a tree of cats categories of feeds feeds each.
+func benchTree(cats, feeds int) *FeedTreeItem {
+	root := &FeedTreeItem{Name: "/", Type: Category}
+	for c := 1; c <= cats; c++ {
+		cat := FeedTreeItem{ID: c, Name: fmt.Sprint("Cat ", c),
+			Type: Category}
+		for f := 1; f <= feeds; f++ {
+			cat.Items = append(cat.Items, FeedTreeItem{ID: c*feeds + f,
+				Name: fmt.Sprint("Feed ", f), Type: Feed})
+		}
+		root.Items = append(root.Items, cat)
+	}
+	return root
+}
+
+func BenchmarkIndexLookup(b *testing.B) {
+	root := benchTree(50, 40)
+	index := root.Index()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index.Path(Feed, 50*40+i%40+1)
+	}
+}
+
+func BenchmarkLinearLookup(b *testing.B) {
+	root := benchTree(50, 40)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		linearLookup(root, "", Feed, 50*40+i%40+1)
+	}
+}
+
+func TestWalkFeedTree(t *testing.T) {
+	stopErr := errors.New("stop")
+	tests := []struct {
+		name    string
+		stopAt  string // the item to return err for
+		err     error
+		want    string
+		wantErr error
+	}{
+		{"everything", "", nil, "/ Special Starred All News Also 1 " +
+			"Tech/Science Go Blog Uncategorized Lonely All again", nil},
+		{"skip a category", "News", filepath.SkipDir, "/ Special Starred " +
+			"All News Uncategorized Lonely All again", nil},
+		{"skip beside a feed", "Starred", filepath.SkipDir, "/ Special " +
+			"Starred News Also 1 Tech/Science Go Blog Uncategorized Lonely " +
+			"All again", nil},
+		{"stop", "Tech/Science", stopErr, "/ Special Starred All News " +
+			"Also 1 Tech/Science", stopErr},
+	}
+	for _, test := range tests {
+		var visited []string
+		err := WalkFeedTree(testTree(), func(item *FeedTreeItem) error {
+			visited = append(visited, item.Name)
+			if item.Name == test.stopAt {
+				return test.err
+			}
+			return nil
+		})
+		got := strings.Join(visited, " ")
+		if got != test.want || err != test.wantErr {
+			t.Errorf("%s: visited %q, returned %v; want %q, %v",
+				test.name, got, err, test.want, test.wantErr)
+		}
+	}
+
+	// A feed's SkipDir, at the top, is no error either.
+	feed := &FeedTreeItem{ID: 5, Name: "Lonely", Type: Feed}
+	err := WalkFeedTree(feed, func(*FeedTreeItem) error {
+		return filepath.SkipDir
+	})
+	if err != nil {
+		t.Errorf("WalkFeedTree on a feed returning SkipDir: got %v, "+
+			"want nil", err)
+	}
+}
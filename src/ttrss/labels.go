@@ -0,0 +1,147 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Label is a label as getLabels describes it.
+// Its ID is the ID of the label's feed, below LABEL_BASE_INDEX, as the API
+// uses throughout, rather than the label's own ID in the database.
+type Label struct {
+	ID      int
+	Caption string
+	FgColor string
+	BgColor string
+
+	// Checked reports whether the article GetLabels was asked about has
+	// the label.
+	Checked bool
+}
+
+// UnmarshalJSON decodes a label as sent by the API, whose ID may arrive as
+// a number or a string.
+func (label *Label) UnmarshalJSON(data []byte) error {
+	var wire struct {
+		ID      json.Number
+		Caption string
+		FgColor string `json:"fg_color"`
+		BgColor string `json:"bg_color"`
+		Checked bool
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	id, err := wire.ID.Int64()
+	if err != nil {
+		return fmt.Errorf("label has bad id %q: %v", wire.ID, err)
+	}
+	*label = Label{
+		ID:      int(id),
+		Caption: wire.Caption,
+		FgColor: wire.FgColor,
+		BgColor: wire.BgColor,
+		Checked: wire.Checked,
+	}
+	return nil
+}
+
+// GetLabels lists every label. If articleID isn't zero, each is Checked if
+// that article has it.
+func (tt *Client) GetLabels(articleID int) (labels []Label, err error) {
+	getMap := map[string]interface{}{}
+	if articleID != 0 {
+		getMap["article_id"] = articleID
+	}
+	resp, err := tt.Call("getLabels", getMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("getLabels: %w", resp.Error)
+		return
+	}
+
+	err = json.Unmarshal(resp.RawContent, &labels)
+	if err != nil {
+		err = fmt.Errorf("getLabels: content is not a list of labels: %v",
+			err)
+	}
+	return
+}
+
+// SetArticleLabel gives each of the articles with the given IDs the label
+// with ID labelID, or if assign is false, takes it away.
+func (tt *Client) SetArticleLabel(ids []int, labelID int, assign bool) (
+	err error) {
+	idStrings := make([]string, len(ids))
+	for i, id := range ids {
+		idStrings[i] = strconv.Itoa(id)
+	}
+	setMap := map[string]interface{}{
+		"article_ids": strings.Join(idStrings, ","),
+		"label_id":    labelID,
+		"assign":      assign,
+	}
+	resp, err := tt.Call("setArticleLabel", setMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("setArticleLabel: %w", resp.Error)
+	}
+	return
+}
+
+// The stock API can't create or delete labels. AddLabel and RemoveLabel use
+// ops a server plugin can provide (see API.md); without one, they fail with
+// an *UnsupportedOpError.
+
+// AddLabel creates a label captioned caption, and returns its ID.
+func (tt *Client) AddLabel(caption string) (labelID int, err error) {
+	addMap := map[string]interface{}{
+		"caption": caption,
+	}
+	resp, err := tt.Call("addLabel", addMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("addLabel: %w", resp.Error)
+		return
+	}
+
+	id, ok := resp.Content["label_id"].(float64)
+	if !ok {
+		err = fmt.Errorf("addLabel: no label ID: have instead %#v",
+			resp.Content)
+		return
+	}
+	labelID = int(id)
+	return
+}
+
+// RemoveLabel deletes the label with ID labelID, taking it off every article
+// that has it.
+func (tt *Client) RemoveLabel(labelID int) (err error) {
+	removeMap := map[string]interface{}{
+		"label_id": labelID,
+	}
+	resp, err := tt.Call("removeLabel", removeMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("removeLabel: %w", resp.Error)
+	}
+	return
+}
@@ -0,0 +1,58 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The stock API can neither read nor change an article's tags; the web UI
+// does it through its own backend. The calls in this file use ops a server
+// plugin can provide (see API.md); without one, they fail with an
+// *UnsupportedOpError.
+
+// GetArticleTags returns the tags on the article with ID articleID.
+func (tt *Client) GetArticleTags(articleID int) (tags []string, err error) {
+	getMap := map[string]interface{}{
+		"article_id": articleID,
+	}
+	resp, err := tt.Call("getArticleTags", getMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("getArticleTags: %w", resp.Error)
+		return
+	}
+
+	var content struct {
+		Tags []string `json:"tags"`
+	}
+	if err = json.Unmarshal(resp.RawContent, &content); err != nil {
+		err = fmt.Errorf("getArticleTags: unexpected content: %v", err)
+		return
+	}
+	return content.Tags, nil
+}
+
+// SetArticleTags replaces the tags on the article with ID articleID with
+// tags. The server keeps tags in a comma-separated list, so no tag can
+// contain a comma.
+func (tt *Client) SetArticleTags(articleID int, tags []string) (err error) {
+	setMap := map[string]interface{}{
+		"article_id": articleID,
+		"tags":       strings.Join(tags, ","),
+	}
+	resp, err := tt.Call("setArticleTags", setMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("setArticleTags: %w", resp.Error)
+	}
+	return
+}
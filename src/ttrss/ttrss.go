@@ -8,6 +8,7 @@ import (
 	"errors"
 	"path/filepath"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 )
@@ -40,10 +41,28 @@ const (
 	FEED_RECENTLY_READ = -6
 )
 
+// DefaultMaxResponseBytes is the response size limit used when
+// Client.MaxResponseBytes is zero.
+const DefaultMaxResponseBytes = 8 << 20
+
 type Client struct {
 	ApiEP     string
 	Client    http.Client
 	SessionID string
+
+	// MaxResponseBytes bounds how much of a response Call will read.
+	// Zero means DefaultMaxResponseBytes.
+	MaxResponseBytes int64
+
+	// OnRequest, if set, is called by Call just before it sends a request.
+	// body is the complete request, including "op" and "sid" (and, for
+	// login, the password), so take care what you log.
+	OnRequest func(op string, body map[string]interface{})
+
+	// OnResponse, if set, is called by Call as it returns.
+	// resp and err are what Call is about to return.
+	OnResponse func(op string, body map[string]interface{}, resp Resp,
+		err error)
 }
 
 // Resp represents the JSON response returned by the TTRSS API.
@@ -59,18 +78,81 @@ type Resp struct {
 	Error error
 
 	// Content of the response.
+	// Empty if the content is not a JSON object.
 	Content map[string]interface{}
+
+	// RawContent is the content of the response as sent.
+	// Some ops, like getHeadlines, return an array rather than an object;
+	// decode this to get at it.
+	RawContent json.RawMessage
+}
+
+// UnmarshalJSON fills in both Content and RawContent.
+func (resp *Resp) UnmarshalJSON(data []byte) error {
+	var wire struct {
+		Seq     int
+		Status  int
+		Content json.RawMessage
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+
+	resp.Seq = wire.Seq
+	resp.Status = wire.Status
+	resp.RawContent = wire.Content
+	resp.Content = nil
+	if len(wire.Content) > 0 && wire.Content[0] == '{' {
+		return json.Unmarshal(wire.Content, &resp.Content)
+	}
+	return nil
+}
+
+// ErrNotLoggedIn is returned by Call when asked to make a call that needs
+// a session before Login has succeeded.
+var ErrNotLoggedIn = errors.New("not authenticated; login first")
+
+// UnsupportedOpError is the Resp.Error of a call to an op the server
+// doesn't offer. Stock servers lack some ops, like moveFeed, that a plugin
+// can add.
+type UnsupportedOpError struct {
+	Op string
+}
+
+func (err *UnsupportedOpError) Error() string {
+	return fmt.Sprintf("server does not offer API op %q; "+
+		"it may need a plugin", err.Op)
+}
+
+// loginFreeOps are the ops the server will answer without a session.
+var loginFreeOps = map[string]bool{
+	"login":      true,
+	"isLoggedIn": true,
 }
 
 // Call issues an API request.
-// If an error status is returned, tt.Error will be set.
+// If an error status is returned, tt.Error will be set; for an op the
+// server doesn't know, to an *UnsupportedOpError.
 // If an HTTP connection error occurs, returns nil and an error.
+// If op needs a session and there is none, returns ErrNotLoggedIn without
+// making a request.
 func (tt *Client) Call(op string, body map[string]interface{}) (resp Resp, err error) {
 	body["op"] = op
 	if tt.SessionID != "" {
 		body["sid"] = tt.SessionID
+	} else if !loginFreeOps[op] {
+		err = ErrNotLoggedIn
+		return
+	}
+
+	if tt.OnRequest != nil {
+		tt.OnRequest(op, body)
+	}
+	if tt.OnResponse != nil {
+		defer func() {
+			tt.OnResponse(op, body, resp, err)
+		}()
 	}
-	fmt.Println("### issuing call:", body)
 
 	buffer, err := AsJSONBuffer(body)
 	if err != nil {
@@ -84,8 +166,18 @@ func (tt *Client) Call(op string, body map[string]interface{}) (resp Resp, err e
 	}
 
 	defer httpResp.Body.Close()
-	dec := json.NewDecoder(httpResp.Body)
+	maxBytes := tt.MaxResponseBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxResponseBytes
+	}
+	limited := io.LimitReader(httpResp.Body, maxBytes).(*io.LimitedReader)
+	dec := json.NewDecoder(limited)
 	err = dec.Decode(&resp)
+	if err != nil && limited.N <= 0 {
+		err = fmt.Errorf("API response too large: more than %d bytes - "+
+			"are you sure you supplied the correct URL?\n", maxBytes)
+		return
+	}
 	if err != nil {
 		err = fmt.Errorf("API JSON response was malformed: %v - "+
 			"are you sure you supplied the correct URL?\n", err)
@@ -96,12 +188,14 @@ func (tt *Client) Call(op string, body map[string]interface{}) (resp Resp, err e
 	if apiError, ok := resp.Content["error"]; ok {
 		if errorString, ok := apiError.(string); ok {
 			resp.Error = errors.New(errorString)
+			if errorString == "UNKNOWN_METHOD" {
+				resp.Error = &UnsupportedOpError{op}
+			}
 		}
 	}
 	if resp.Status != API_STATUS_OK && resp.Error == nil {
 		resp.Error = errors.New("(response contained no error text)")
 	}
-	fmt.Println("###", op, "status:", resp.Status)
 	return
 }
 
@@ -111,16 +205,20 @@ type ConnInfo struct {
 	Password string
 }
 
-// Logs into the host as the designated user.
-// Updates tt.ApiEP and tt.SessionID if successful.
-func (tt *Client) Login(conn ConnInfo) (ok bool, err error) {
-	apiEP := conn.HostURL
+// APIEndpoint returns the address of the API of the instance at hostURL.
+func APIEndpoint(hostURL string) string {
+	apiEP := hostURL
 	if !strings.HasSuffix(apiEP, "/") {
 		apiEP += "/"
 	}
-	apiEP += "api/"
+	return apiEP + "api/"
+}
+
+// Logs into the host as the designated user.
+// Updates tt.ApiEP and tt.SessionID if successful.
+func (tt *Client) Login(conn ConnInfo) (ok bool, err error) {
+	apiEP := APIEndpoint(conn.HostURL)
 	tt.ApiEP = apiEP
-	fmt.Println("### trying to log in as", conn.User, "at", apiEP)
 
 	loginMap := map[string]interface{}{
 		"user":     conn.User,
@@ -142,7 +240,33 @@ func (tt *Client) Login(conn ConnInfo) (ok bool, err error) {
 		return
 	}
 	tt.SessionID = sessionID.(string)
-	fmt.Println("### logged in as", conn.User, "with sessionID", tt.SessionID)
+	return
+}
+
+// GetApiLevel returns the server's API level, which counts up as the API
+// gains ops and parameters. Servers that predate the op are level 0.
+func (tt *Client) GetApiLevel() (level int, err error) {
+	resp, err := tt.Call("getApiLevel", map[string]interface{}{})
+	if err != nil {
+		return
+	}
+
+	var unsupported *UnsupportedOpError
+	if errors.As(resp.Error, &unsupported) {
+		return 0, nil
+	}
+	if resp.Error != nil {
+		err = fmt.Errorf("getApiLevel: %w", resp.Error)
+		return
+	}
+
+	l, ok := resp.Content["level"].(float64)
+	if !ok {
+		err = fmt.Errorf("getApiLevel: no level: have instead %#v",
+			resp.Content)
+		return
+	}
+	level = int(l)
 	return
 }
 
@@ -176,7 +300,7 @@ func (status SubscribeStatus) String() (text string) {
 	case SUB_XML_INVALID:
 		text = "invalid XML at URL"
 	default:
-		fmt.Sprintf("unknown Subscribe status: %d", status)
+		text = fmt.Sprintf("unknown Subscribe status: %d", int(status))
 	}
 	return
 }
@@ -186,6 +310,10 @@ type SubscribeError struct {
 
 	// Error message provided by the API.
 	Message string
+
+	// FeedID is the ID of the feed subscribed to, if the server says.
+	// Older servers don't, and leave it zero.
+	FeedID int
 }
 
 func (err *SubscribeError) Error() (text string) {
@@ -225,7 +353,7 @@ func (tt *Client) Subscribe(feedURL string, categoryID int, feedUsername string,
 
 	jsonCode, ok := subscribeStatus["code"].(float64)
 	code := SubscribeStatus(jsonCode)
-	if tok := SUB_ADDED <= code && code <= SUB_XML_INVALID; !ok || !tok {
+	if tok := SUB_ALREADY_ADDED <= code && code <= SUB_XML_INVALID; !ok || !tok {
 		err = fmt.Errorf("Unknown SubscribeStatus: %#v",
 			subscribeStatus)
 		return
@@ -236,17 +364,35 @@ func (tt *Client) Subscribe(feedURL string, categoryID int, feedUsername string,
 		message = "(no underlying error returned by API)"
 	}
 
-	err = &SubscribeError{code, message}
+	feedID, _ := subscribeStatus["feed_id"].(float64)
+	err = &SubscribeError{code, message, int(feedID)}
 
 	didSubscribe = code == SUB_ADDED || code == SUB_ALREADY_ADDED
 	return
 }
 
+// Unsubscribe removes the feed with the given ID, along with its articles.
+func (tt *Client) Unsubscribe(feedID int) (err error) {
+	unsubscribeMap := map[string]interface{}{
+		"feed_id": feedID,
+	}
+	resp, err := tt.Call("unsubscribeFeed", unsubscribeMap)
+	if err != nil {
+		return
+	}
+
+	if resp.Error != nil {
+		err = fmt.Errorf("unsubscribeFeed: API error: %s", resp.Error)
+	}
+	return
+}
+
 const Category = "category"
 const Feed = "feed"
 
 // FeedTreeItem represents an item in the feed tree returned by GetFeedTree.
 type FeedTreeItem struct {
+	// ID is CATEGORY_UNCATEGORIZED for the synthetic root node.
 	ID int `json:"bare_ID"`
 	// Name is "/" when it is the synthetic root node.
 	Name string
@@ -259,40 +405,63 @@ type FeedTreeItem struct {
 	Items []FeedTreeItem
 }
 
+// IsRoot reports whether item is the synthetic root node of a tree returned
+// by GetFeedTree, whose Items are the top-level categories.
+func (item *FeedTreeItem) IsRoot() bool {
+	return item.Type == Category && item.Name == "/"
+}
+
+// IsVirtual reports whether item is provided by the server rather than the
+// user, like the Special category and its Starred articles feed.
+// Note that feed 0 is Archived articles, while category 0 is Uncategorized.
+func (item *FeedTreeItem) IsVirtual() bool {
+	if item.Type == Category {
+		return item.ID < 0
+	}
+	return item.ID <= 0
+}
+
 // See filepath.WalkFunc. This is similar, but no errors can occur while
 // walking an already-fetched tree. Use filepath.SkipDir to continue in the
 // current category but not recurse.
 type WalkFeedTreeFunc func(item *FeedTreeItem) error
 
+// WalkFeedTree calls walkFn on tree and then, if it's a category, on
+// everything in it, depth first, in the order the server gave them, as
+// filepath.Walk walks a directory. Returned from a category, filepath.SkipDir
+// skips what's in it; from a feed, the rest of the feeds and categories
+// beside it. Any other error stops the walk, and is returned.
 func WalkFeedTree(tree *FeedTreeItem, walkFn WalkFeedTreeFunc) error {
-	var err error
-	switch tree.Type {
-	case Category:
-		err = walkFn(tree)
-		if err != nil {
-			return err
-		}
+	err := walkFeedTree(tree, walkFn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
 
-		for _, item := range tree.Items {
-			isCat := item.Type == Category
-			err = walkFn(&item)
-			if !isCat && err != filepath.SkipDir {
-				return err
-			}
-			if isCat {
-				err = WalkFeedTree(&item, walkFn)
-				if err != nil {
-					return err
-				}
-			}
+func walkFeedTree(item *FeedTreeItem, walkFn WalkFeedTreeFunc) error {
+	err := walkFn(item)
+	if item.Type != Category {
+		return err
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := range item.Items {
+		err = walkFeedTree(&item.Items[i], walkFn)
+		if err == filepath.SkipDir {
+			// A feed asked to skip the rest of this category.
+			return nil
 		}
-	case Feed:
-		err = walkFn(tree)
 		if err != nil {
 			return err
 		}
 	}
-	return err
+	return nil
 }
 
 func (tt *Client) GetFeedTree(includeEmptyCategories bool) (root FeedTreeItem, err error) {
@@ -305,7 +474,7 @@ func (tt *Client) GetFeedTree(includeEmptyCategories bool) (root FeedTreeItem, e
 	}
 
 	if resp.Status != API_STATUS_OK {
-		err = fmt.Errorf("failed to get feed tree: API returned status",
+		err = fmt.Errorf("failed to get feed tree: API returned status %d",
 			resp.Status)
 		return
 	}
@@ -316,27 +485,33 @@ func (tt *Client) GetFeedTree(includeEmptyCategories bool) (root FeedTreeItem, e
 		return
 	}
 
-	type jsonObject map[string]interface{}
-	categories, ok := maybeCategories.(jsonObject)
+	categories, ok := maybeCategories.(map[string]interface{})
 	if !ok {
 		err = fmt.Errorf("getFeedTree: categories is not a JSON object: %#v",
 			maybeCategories)
 		return
 	}
 
+	root.Name = "/"
+	root.Type = Category
+
+	// A brand-new account may have no items at all, rather than an empty
+	// list of them. Either way, that is an empty tree, not an error.
 	maybeItems, ok := categories["items"]
-	if (!ok) {
-		err = fmt.Errorf("getFeedTree: categories has no items entry")
+	if !ok || maybeItems == nil {
 		return
 	}
 
-	items, ok := maybeItems.([]jsonObject)
-	if !ok {
-		err = fmt.Errorf("getFeedTree: items is not a JSON array: %T", maybeItems)
+	// Round-trip through JSON so the decoder can fill in the tree for us.
+	buffer, err := AsJSONBuffer(maybeItems)
+	if err != nil {
 		return
 	}
-
-	err = fmt.Errorf("BUG: getting there: items %v", items)
+	err = json.NewDecoder(&buffer).Decode(&root.Items)
+	if err != nil {
+		err = fmt.Errorf("getFeedTree: items is not a JSON array of items: %v",
+			err)
+	}
 	return
 }
 
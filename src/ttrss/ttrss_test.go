@@ -0,0 +1,178 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package ttrss
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubClient returns a Client logged in to a server that answers every call
+// with content, closed when the test ends.
+func stubClient(t *testing.T, content string) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"seq":0,"status":0,"content":` + content + `}`))
+		}))
+	t.Cleanup(srv.Close)
+	return &Client{ApiEP: APIEndpoint(srv.URL), SessionID: "SID"}
+}
+
+func TestGetFeedTreeEmpty(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no items", `{"categories":{"identifier":"id"}}`},
+		{"null items", `{"categories":{"identifier":"id","items":null}}`},
+		{"empty items", `{"categories":{"identifier":"id","items":[]}}`},
+	}
+	for _, test := range tests {
+		tt := stubClient(t, test.content)
+		root, err := tt.GetFeedTree(true)
+		if err != nil {
+			t.Errorf("%s: GetFeedTree: %v", test.name, err)
+			continue
+		}
+		if !root.IsRoot() || len(root.Items) != 0 {
+			got, _ := json.Marshal(root)
+			t.Errorf("%s: GetFeedTree = %s, want an empty root",
+				test.name, got)
+		}
+	}
+}
+
+func TestCallHooks(t *testing.T) {
+	tt := stubClient(t, `{"level":3}`)
+	var requested, responded []string
+	tt.OnRequest = func(op string, body map[string]interface{}) {
+		requested = append(requested, op)
+		if body["sid"] != "SID" {
+			t.Errorf("OnRequest(%q): body %v lacks the session", op, body)
+		}
+	}
+	tt.OnResponse = func(op string, body map[string]interface{},
+		resp Resp, err error) {
+		responded = append(responded, op)
+		if err != nil || resp.Content["level"] != 3.0 {
+			t.Errorf("OnResponse(%q): got %+v, %v; want level 3",
+				op, resp, err)
+		}
+	}
+
+	for _, op := range []string{"getApiLevel", "getVersion"} {
+		if _, err := tt.Call(op, map[string]interface{}{}); err != nil {
+			t.Fatalf("Call(%q): %v", op, err)
+		}
+	}
+	want := "[getApiLevel getVersion]"
+	if got := fmt.Sprint(requested); got != want {
+		t.Errorf("OnRequest saw %s, want %s", got, want)
+	}
+	if got := fmt.Sprint(responded); got != want {
+		t.Errorf("OnResponse saw %s, want %s", got, want)
+	}
+}
+
+func TestCallHooksOnError(t *testing.T) {
+	tt := stubClient(t, `{}`)
+	tt.ApiEP = "http://127.0.0.1:0/api/"
+	var gotErr error
+	tt.OnResponse = func(op string, body map[string]interface{},
+		resp Resp, err error) {
+		gotErr = err
+	}
+	_, err := tt.Call("getApiLevel", map[string]interface{}{})
+	if err == nil || gotErr != err {
+		t.Errorf("Call to nowhere: got %v, OnResponse got %v; "+
+			"want the same error", err, gotErr)
+	}
+}
+
+func TestCallWithoutHooks(t *testing.T) {
+	tt := stubClient(t, `{"level":3}`)
+	resp, err := tt.Call("getApiLevel", map[string]interface{}{})
+	if err != nil || resp.Content["level"] != 3.0 {
+		t.Errorf("Call without hooks: got %+v, %v; want level 3", resp, err)
+	}
+}
+
+func TestCallMaxResponseBytes(t *testing.T) {
+	padding := `"` + strings.Repeat("x", 1000) + `"`
+	tests := []struct {
+		name     string
+		maxBytes int64
+		tooLarge bool
+	}{
+		{"default", 0, false},
+		{"negative means default", -1, false},
+		{"room to spare", 2000, false},
+		{"too large", 100, true},
+	}
+	for _, test := range tests {
+		tt := stubClient(t, `{"padding":`+padding+`}`)
+		tt.MaxResponseBytes = test.maxBytes
+		_, err := tt.Call("getVersion", map[string]interface{}{})
+		tooLarge := err != nil &&
+			strings.Contains(err.Error(), "API response too large")
+		if tooLarge != test.tooLarge || err != nil && !tooLarge {
+			t.Errorf("%s: Call with MaxResponseBytes %d: %v", test.name,
+				test.maxBytes, err)
+		}
+	}
+}
+
+func TestCallNotLoggedIn(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.Write([]byte(`{"seq":0,"status":0,"content":{}}`))
+		}))
+	defer srv.Close()
+	tt := &Client{ApiEP: APIEndpoint(srv.URL)}
+
+	tests := []struct {
+		op   string
+		want error
+	}{
+		{"getFeedTree", ErrNotLoggedIn},
+		{"subscribeToFeed", ErrNotLoggedIn},
+		{"login", nil},
+		{"isLoggedIn", nil},
+	}
+	for _, test := range tests {
+		before := requests
+		_, err := tt.Call(test.op, map[string]interface{}{})
+		sent := requests > before
+		if err != test.want || sent != (test.want == nil) {
+			t.Errorf("Call(%q) without a session: got %v, sent: %v; "+
+				"want %v", test.op, err, sent, test.want)
+		}
+	}
+}
+
+func TestGetApiLevel(t *testing.T) {
+	tests := []struct {
+		content string
+		want    int
+		wantErr bool
+	}{
+		{`{"level":15}`, 15, false},
+		{`{"error":"UNKNOWN_METHOD"}`, 0, false},
+		{`{"error":"NOT_LOGGED_IN"}`, 0, true},
+		{`{}`, 0, true},
+	}
+	for _, test := range tests {
+		level, err := stubClient(t, test.content).GetApiLevel()
+		if level != test.want || (err != nil) != test.wantErr {
+			t.Errorf("GetApiLevel answered %s: got %d, %v; want %d, "+
+				"error: %v", test.content, level, err, test.want,
+				test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ttrss"
+)
+
+type Star struct {
+	flHelp   bool
+	flUnstar bool
+	flFrom   string
+	flLatest bool
+	flags    flag.FlagSet
+}
+
+func (star *Star) Init() {
+	star.flags.Init("star", flag.PanicOnError)
+
+	star.flags.BoolVar(&star.flHelp, "h", false, "help")
+	star.flags.BoolVar(&star.flHelp, "help", false, "help")
+
+	star.flags.BoolVar(&star.flUnstar, "u", false, "unstar the articles")
+	star.flags.StringVar(&star.flFrom, "from", "",
+		"star an article from the feed or category at `catpath`")
+	star.flags.BoolVar(&star.flLatest, "latest", false,
+		"with --from, star its newest article")
+}
+
+func (star *Star) Flags() *flag.FlagSet {
+	return &star.flags
+}
+
+func (star *Star) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "star [-u] article_id... -- star articles")
+	fmt.Fprintln(w, "star [-u] --from catpath --latest -- star the newest "+
+		"article in a feed")
+}
+
+// Run stars each article given by ID, or with -u, unstars it. An ID of "-"
+// stands for the IDs read from stdin.
+// With --from and --latest, the newest article in a feed or category is
+// starred too.
+// It exits EX_DATAERR if an ID is malformed, EX_NOINPUT if --from names
+// nothing or finds no articles, and EX_UNAVAILABLE if the server refused.
+// The server says nothing of IDs that name no article.
+func (star *Star) Run(args []string) {
+	star.flags.Parse(args)
+
+	if star.flHelp {
+		flagSetPrintUsage(star.flags, os.Stdout, "star")
+		exit(EX_SUCCESS)
+	}
+
+	from := star.flFrom != ""
+	if star.flags.NArg() < 1 && !from || from != star.flLatest {
+		flagSetPrintUsage(star.flags, os.Stderr, "star")
+		exit(EX_USAGE)
+	}
+
+	ids, err := parseArticleIDs(star.flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "star:", err)
+		exit(EX_DATAERR)
+	}
+	if from {
+		item, err := ResolveCatPath(star.flFrom)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "star:", err)
+			printCandidates(err)
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				exit(EX_NOINPUT)
+			}
+			exit(EX_DATAERR)
+		}
+		latest, err := latestHeadline(item)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "star: %s: %v\n", star.flFrom, err)
+			exit(EX_UNAVAILABLE)
+		}
+		if latest == nil {
+			fmt.Fprintf(os.Stderr, "star: no articles in %q\n", star.flFrom)
+			exit(EX_NOINPUT)
+		}
+		infof("star: newest in %s is %d: %s", display(star.flFrom),
+			latest.ID, display(latest.Title))
+		ids = append(ids, latest.ID)
+	}
+
+	if len(ids) == 0 {
+		exit(EX_SUCCESS)
+	}
+
+	mode := ttrss.UPDATE_TRUE
+	if star.flUnstar {
+		mode = ttrss.UPDATE_FALSE
+	}
+	if err := updateArticles("star", ids, ttrss.FIELD_STARRED,
+		mode); err != nil {
+		fmt.Fprintln(os.Stderr, "star:", err)
+		exit(EX_UNAVAILABLE)
+	}
+	exit(EX_SUCCESS)
+}
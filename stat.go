@@ -0,0 +1,168 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"ttrss"
+)
+
+type Stat struct {
+	flHelp      bool
+	flPorcelain porcelainValue
+	flags       flag.FlagSet
+}
+
+func (stat *Stat) Init() {
+	stat.flags.Init("stat", flag.PanicOnError)
+
+	stat.flags.BoolVar(&stat.flHelp, "h", false, "help")
+	stat.flags.BoolVar(&stat.flHelp, "help", false, "help")
+
+	stat.flags.Var(&stat.flPorcelain, "porcelain", porcelainUsage)
+}
+
+func (stat *Stat) Flags() *flag.FlagSet {
+	return &stat.flags
+}
+
+func (stat *Stat) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "stat [--porcelain] catpath... "+
+		"-- describe feeds and categories")
+}
+
+// Run describes each feed or category named as "key: value" lines, with
+// a blank line between them.
+// The API doesn't tell us a feed's site URL or update interval, so those
+// are missing.
+// With --porcelain, each line is a key and a value separated by a tab, both
+// escaped for scripts, and the last update is a Unix time, or 0 if never.
+func (stat *Stat) Run(args []string) {
+	stat.flags.Parse(args)
+
+	if stat.flHelp {
+		flagSetPrintUsage(stat.flags, os.Stdout, "stat")
+		exit(EX_SUCCESS)
+	}
+
+	if stat.flags.NArg() < 1 {
+		flagSetPrintUsage(stat.flags, os.Stderr, "stat")
+		exit(EX_USAGE)
+	}
+
+	// The tree gives us the catpaths, resolved against it alone; getFeeds,
+	// the rest.
+	tree, err := tt.GetFeedTree(true)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "stat:", err)
+		exit(EX_UNAVAILABLE)
+	}
+	index := tree.Index()
+	feeds, err := tt.GetFeeds(ttrss.CATEGORY_FEEDS_NOT_VIRTUAL, false, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "stat:", err)
+		exit(EX_UNAVAILABLE)
+	}
+	feedByID := make(map[int]ttrss.FeedInfo, len(feeds))
+	for _, feed := range feeds {
+		feedByID[feed.ID] = feed
+	}
+
+	code := EX_SUCCESS
+	described := 0
+	for _, catpath := range stat.flags.Args() {
+		item, err := resolveCatPathIn(&tree, catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "stat:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		if described > 0 {
+			fmt.Println()
+		}
+		described++
+		stat.field("path", catpath)
+		stat.field("type", item.Type)
+		stat.field("id", fmt.Sprint(item.ID))
+		if item.Type == ttrss.Category {
+			stat.statCategory(item, feedByID)
+		} else {
+			stat.statFeed(item, feedByID, index)
+		}
+	}
+	exit(code)
+}
+
+func (stat *Stat) statFeed(item *ttrss.FeedTreeItem,
+	feedByID map[int]ttrss.FeedInfo, index *ttrss.FeedTreeIndex) {
+	feed, ok := feedByID[item.ID]
+	if !ok {
+		// Virtual feeds have no more to tell.
+		return
+	}
+
+	stat.field("url", feed.FeedURL)
+	category := index.Path(ttrss.Category, feed.CategoryID)
+	if category == "" {
+		category = "/"
+	}
+	stat.field("category", category)
+	updated := "never"
+	switch {
+	case stat.flPorcelain != "" && feed.LastUpdated.Unix() > 0:
+		updated = fmt.Sprint(feed.LastUpdated.Unix())
+	case stat.flPorcelain != "":
+		updated = "0"
+	case feed.LastUpdated.Unix() > 0:
+		updated = feed.LastUpdated.Format(time.RFC3339)
+	}
+	stat.field("updated", updated)
+	stat.field("error", item.LastError)
+	stat.field("unread", fmt.Sprint(feed.Unread))
+}
+
+func (stat *Stat) statCategory(cat *ttrss.FeedTreeItem,
+	feedByID map[int]ttrss.FeedInfo) {
+	categories, feeds, unread := countCategory(cat, feedByID)
+	stat.field("categories", fmt.Sprint(categories))
+	stat.field("feeds", fmt.Sprint(feeds))
+	stat.field("unread", fmt.Sprint(unread))
+}
+
+// countCategory counts the categories and feeds below cat, however deep,
+// and their unread articles, as feedByID gives them.
+func countCategory(cat *ttrss.FeedTreeItem,
+	feedByID map[int]ttrss.FeedInfo) (categories, feeds, unread int) {
+	walkCatPath(cat, "", func(item *ttrss.FeedTreeItem, _ string) bool {
+		switch {
+		case item == cat:
+		case item.Type == ttrss.Category:
+			categories++
+		default:
+			feeds++
+			unread += feedByID[item.ID].Unread
+		}
+		return true
+	})
+	return
+}
+
+// field prints one line of the description: key, and its value.
+func (stat *Stat) field(key, value string) {
+	if stat.flPorcelain != "" {
+		printPorcelain(os.Stdout, key, value)
+		return
+	}
+	fmt.Printf("%s: %s\n", key, display(value))
+}
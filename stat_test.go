@@ -0,0 +1,71 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"testing"
+	"ttrss"
+)
+
+func TestCountCategory(t *testing.T) {
+	feed := func(id int, name string) ttrss.FeedTreeItem {
+		return ttrss.FeedTreeItem{ID: id, Name: name, Type: ttrss.Feed}
+	}
+	cat := func(id int, name string,
+		items ...ttrss.FeedTreeItem) ttrss.FeedTreeItem {
+		return ttrss.FeedTreeItem{ID: id, Name: name, Type: ttrss.Category,
+			Items: items}
+	}
+	feedByID := map[int]ttrss.FeedInfo{
+		10: {Unread: 1}, 11: {Unread: 2}, 12: {Unread: 4}, 13: {Unread: 8},
+	}
+
+	tests := []struct {
+		name                            string
+		cat                             ttrss.FeedTreeItem
+		wantCats, wantFeeds, wantUnread int
+	}{
+		{"empty", cat(1, "Empty"), 0, 0, 0},
+		{"flat", cat(1, "News", feed(10, "A"), feed(11, "B")), 0, 2, 3},
+		{"nested", cat(1, "News", feed(10, "A"), feed(11, "B"),
+			cat(2, "Sub", feed(12, "C"))), 1, 3, 7},
+		{"nested first", cat(1, "News", cat(2, "Sub", feed(12, "C")),
+			feed(10, "A")), 1, 2, 5},
+		{"deeper", cat(1, "News", cat(2, "Sub", cat(3, "Subsub",
+			feed(13, "D")), cat(4, "Empty")), feed(10, "A")), 3, 2, 9},
+		{"no counts", cat(1, "News", feed(20, "New")), 0, 1, 0},
+	}
+	for _, test := range tests {
+		cats, feeds, unread := countCategory(&test.cat, feedByID)
+		if cats != test.wantCats || feeds != test.wantFeeds ||
+			unread != test.wantUnread {
+			t.Errorf("%s: countCategory = %d categories, %d feeds, "+
+				"%d unread; want %d, %d, %d", test.name, cats, feeds,
+				unread, test.wantCats, test.wantFeeds, test.wantUnread)
+		}
+	}
+}
+
+func TestStatSeveral(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News"), catItem(2, "Blogs")),
+		"getFeeds": func(map[string]interface{}) interface{} {
+			return []interface{}{}
+		},
+	})
+
+	stdout, stderr, code := runTool(t, stub, "", "stat", "/Missing",
+		"/News", "/Nowhere", "/Blogs")
+	want := "path: /News\ntype: category\nid: 1\n" +
+		"categories: 0\nfeeds: 0\nunread: 0\n" +
+		"\n" +
+		"path: /Blogs\ntype: category\nid: 2\n" +
+		"categories: 0\nfeeds: 0\nunread: 0\n"
+	if code != EX_NOINPUT || stdout != want {
+		t.Errorf("stat: got exit %d, stderr %q, stdout:\n%q\nwant exit %d "+
+			"and:\n%q", code, stderr, stdout, EX_NOINPUT, want)
+	}
+	if trees := len(stub.called("getFeedTree")); trees != 1 {
+		t.Errorf("stat: fetched the tree %d times, want once", trees)
+	}
+}
@@ -0,0 +1,149 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type Tag struct {
+	flHelp bool
+	flags  flag.FlagSet
+}
+
+func (tag *Tag) Init() {
+	tag.flags.Init("tag", flag.PanicOnError)
+
+	tag.flags.BoolVar(&tag.flHelp, "h", false, "help")
+	tag.flags.BoolVar(&tag.flHelp, "help", false, "help")
+}
+
+func (tag *Tag) Flags() *flag.FlagSet {
+	return &tag.flags
+}
+
+func (tag *Tag) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "tag ls article_id -- list an article's tags")
+	fmt.Fprintln(w, "tag add|rm name article_id... "+
+		"-- tag articles, or untag them")
+}
+
+// Run lists an article's tags, or tags or untags articles, as its first
+// argument says. Article IDs are as for star, "-" and all.
+// Tags are kept as the server keeps them: trimmed and lowercase.
+// It carries on past failures, and exits with the code for the last one:
+// EX_DATAERR if a tag or ID is malformed, and EX_UNAVAILABLE if the server
+// refused. All of it needs a server plugin.
+func (tag *Tag) Run(args []string) {
+	tag.flags.Parse(args)
+
+	if tag.flHelp {
+		flagSetPrintUsage(tag.flags, os.Stdout, "tag")
+		exit(EX_SUCCESS)
+	}
+
+	argc := tag.flags.NArg()
+	switch tag.flags.Arg(0) {
+	case "ls":
+		if argc == 2 {
+			exit(listTags(tag.flags.Arg(1)))
+		}
+	case "add", "rm":
+		if argc >= 3 {
+			exit(setTag(tag.flags.Arg(0), tag.flags.Arg(1),
+				tag.flags.Args()[2:]))
+		}
+	}
+	flagSetPrintUsage(tag.flags, os.Stderr, "tag")
+	exit(EX_USAGE)
+}
+
+// listTags prints each tag on the article with ID arg, and returns the exit
+// code for how that went.
+func listTags(arg string) int {
+	ids, err := parseArticleIDs([]string{arg})
+	if err != nil || len(ids) != 1 {
+		if err == nil {
+			err = fmt.Errorf("not one article ID: %q", arg)
+		}
+		fmt.Fprintln(os.Stderr, "tag:", err)
+		return EX_DATAERR
+	}
+
+	tags, err := tt.GetArticleTags(ids[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tag:", err)
+		return EX_UNAVAILABLE
+	}
+	for _, name := range tags {
+		fmt.Println(display(name))
+	}
+	return EX_SUCCESS
+}
+
+// setTag adds the tag name to each article in args, or if sub is "rm",
+// takes it off, and returns the exit code for how that went.
+// Articles already as asked are left alone.
+func setTag(sub, name string, args []string) (code int) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.Contains(name, ",") {
+		fmt.Fprintf(os.Stderr, "tag: bad tag %q: tags can't be empty, "+
+			"nor have commas in them\n", name)
+		return EX_DATAERR
+	}
+	ids, err := parseArticleIDs(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tag:", err)
+		return EX_DATAERR
+	}
+
+	for _, id := range ids {
+		// Tags can only be set all at once, so read them first.
+		tags, err := tt.GetArticleTags(id)
+		if err == nil {
+			tags, changed := retag(tags, name, sub == "add")
+			if !changed {
+				continue
+			}
+			err = tt.SetArticleTags(id, tags)
+
+			result := "ok"
+			if err != nil {
+				result = err.Error()
+			} else {
+				stats.affected++
+			}
+			logChange(ChangelogEntry{Op: "tag " + sub, ID: id, To: name,
+				Result: result})
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tag: %d: %v\n", id, err)
+			code = EX_UNAVAILABLE
+		}
+	}
+	return
+}
+
+// retag returns tags with name added, or if add is false, removed, and
+// whether that changed anything.
+func retag(tags []string, name string, add bool) (retagged []string,
+	changed bool) {
+	for _, t := range tags {
+		if t == name {
+			if add {
+				return tags, false
+			}
+			changed = true
+			continue
+		}
+		retagged = append(retagged, t)
+	}
+	if add {
+		return append(retagged, name), true
+	}
+	return retagged, changed
+}
@@ -0,0 +1,197 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+	"ttrss"
+)
+
+type Tail struct {
+	flHelp     bool
+	flFollow   bool
+	flCount    int
+	flInterval time.Duration
+	flSinceID  int
+	flags      flag.FlagSet
+}
+
+func (tail *Tail) Init() {
+	tail.flags.Init("tail", flag.PanicOnError)
+
+	tail.flags.BoolVar(&tail.flHelp, "h", false, "help")
+	tail.flags.BoolVar(&tail.flHelp, "help", false, "help")
+
+	followUsage := "keep polling for new articles until interrupted"
+	tail.flags.BoolVar(&tail.flFollow, "f", false, followUsage)
+	tail.flags.BoolVar(&tail.flFollow, "follow", false, followUsage)
+
+	tail.flags.IntVar(&tail.flCount, "n", 10,
+		"print `N` articles: the oldest, or with -f, the newest")
+	tail.flags.DurationVar(&tail.flInterval, "interval", time.Minute,
+		"how long to wait between polls when following")
+	tail.flags.IntVar(&tail.flSinceID, "since-id", 0,
+		"only show articles with IDs greater than `ID`, and report the "+
+			"greatest ID seen on stderr")
+}
+
+func (tail *Tail) Flags() *flag.FlagSet {
+	return &tail.flags
+}
+
+func (tail *Tail) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "tail [-f] [-n N] [--interval D] [--since-id ID] catpath"+
+		" -- print a feed or category's oldest articles, or follow it")
+}
+
+// Run prints the oldest articles the server still keeps in a feed or
+// category, newest first: the bottom of what cat prints, as head prints the
+// top.
+// With -f or --since-id, it reads the feed as a log that grows at the
+// bottom instead: it prints the newest articles (or with --since-id, the
+// oldest after the ID given), oldest first, and with -f, keeps printing
+// those that arrive until interrupted.
+func (tail *Tail) Run(args []string) {
+	tail.flags.Parse(args)
+
+	if tail.flHelp {
+		flagSetPrintUsage(tail.flags, os.Stdout, "tail")
+		exit(EX_SUCCESS)
+	}
+
+	if tail.flags.NArg() != 1 || tail.flInterval <= 0 {
+		flagSetPrintUsage(tail.flags, os.Stderr, "tail")
+		exit(EX_USAGE)
+	}
+
+	catpath := tail.flags.Arg(0)
+	item, err := ResolveCatPath(catpath)
+	if err != nil {
+		printCandidates(err)
+		log.Fatalln(err)
+	}
+
+	if !tail.flFollow && tail.flSinceID == 0 {
+		req := headlinesRequestFor(item)
+		req.Limit = tail.flCount
+		req.OrderBy = ttrss.ORDER_DATE_REVERSE
+		oldest, err := tt.GetHeadlines(req)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for i := len(oldest) - 1; i >= 0; i-- {
+			printHeadline(os.Stdout, oldest[i])
+		}
+		return
+	}
+
+	req := headlinesRequestFor(item)
+	req.Limit = tail.flCount
+	req.SinceID = tail.flSinceID
+	if req.SinceID > 0 {
+		// Take the oldest after the checkpoint, so none are skipped if
+		// more than N have arrived since.
+		req.OrderBy = ttrss.ORDER_DATE_REVERSE
+	}
+	latest, err := tt.GetHeadlines(req)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// Seed the seen set even when printing nothing, so that following
+	// starts from what's there now.
+	seen := make(map[int]bool)
+	sinceID := tail.flSinceID
+	if tail.flSinceID > 0 {
+		// Whether we stop here or on interrupt, tell the caller where to
+		// pick up next time.
+		defer func() {
+			fmt.Fprintf(os.Stderr, "last-id: %d\n", sinceID)
+		}()
+	}
+	oldestFirst(latest)
+	for _, h := range latest {
+		seen[h.ID] = true
+		if h.ID > sinceID {
+			sinceID = h.ID
+		}
+		printHeadline(os.Stdout, h)
+	}
+
+	if !tail.flFollow {
+		return
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt)
+	ticker := time.NewTicker(tail.flInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-interrupted:
+			return
+		case <-ticker.C:
+		}
+
+		req := headlinesRequestFor(item)
+		req.SinceID = sinceID
+		arrived, err := tt.GetHeadlines(req)
+		if err != nil {
+			// The server may just be restarting; try again next time.
+			fmt.Fprintln(os.Stderr, "tail:", err)
+			continue
+		}
+
+		oldestFirst(arrived)
+		for _, h := range arrived {
+			if seen[h.ID] {
+				continue
+			}
+			seen[h.ID] = true
+			if h.ID > sinceID {
+				sinceID = h.ID
+			}
+			printHeadline(os.Stdout, h)
+		}
+	}
+}
+
+// headlinesRequestFor returns a request for the headlines in item,
+// which can be a feed, a category (including its subcategories), or the root.
+func headlinesRequestFor(item *ttrss.FeedTreeItem) (
+	req ttrss.HeadlinesRequest) {
+	switch {
+	case item.IsRoot():
+		req.FeedID = ttrss.FEED_ALL_ARTICLES
+	case item.Type == ttrss.Category:
+		req.FeedID = item.ID
+		req.IsCat = true
+		req.IncludeNested = true
+	default:
+		req.FeedID = item.ID
+	}
+	return
+}
+
+// oldestFirst sorts headlines by increasing article ID, which is the order
+// the server saw them in.
+func oldestFirst(headlines []ttrss.Headline) {
+	sort.Slice(headlines, func(i, j int) bool {
+		return headlines[i].ID < headlines[j].ID
+	})
+}
+
+// printHeadline writes h to w as a single tab-separated line:
+// date, title, link.
+func printHeadline(w io.Writer, h ttrss.Headline) {
+	fmt.Fprintf(w, "%s\t%s\t%s\n",
+		h.Updated.Format("2006-01-02 15:04"), display(h.Title),
+		display(h.Link))
+}
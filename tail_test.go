@@ -0,0 +1,117 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTailFollow polls a feed whose articles arrive over several polls,
+// and which, ignoring since_id, sends some of them more than once.
+func TestTailFollow(t *testing.T) {
+	polls := [][]int{{101, 100}, {102, 101}, {102}, {104, 103}, {104}}
+	var mu sync.Mutex
+	calls := 0
+	polled := make(chan bool)
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "Blog"))),
+		"getHeadlines": func(map[string]interface{}) interface{} {
+			mu.Lock()
+			defer mu.Unlock()
+			ids := polls[len(polls)-1]
+			if calls < len(polls) {
+				ids = polls[calls]
+			}
+			calls++
+			if calls == len(polls) {
+				close(polled)
+			}
+			var headlines []interface{}
+			for _, id := range ids {
+				headlines = append(headlines, map[string]interface{}{
+					"id": id, "title": fmt.Sprint("A", id), "score": 0,
+					"link": "http://example.com/", "updated": 0})
+			}
+			return headlines
+		},
+	})
+
+	cmd, stdout, stderr := startTool(t, stub, "", "tail", "-f",
+		"--interval", "10ms", "/News/Blog")
+	select {
+	case <-polled:
+	case <-time.After(10 * time.Second):
+		cmd.Process.Kill()
+		t.Fatalf("tail -f: gave up waiting for %d polls", len(polls))
+	}
+	cmd.Process.Signal(os.Interrupt)
+	if code := waitTool(t, cmd); code != EX_SUCCESS {
+		t.Errorf("tail -f: exit %d on interrupt, want 0; stderr %q",
+			code, stderr)
+	}
+
+	var titles []string
+	for _, line := range strings.Split(
+		strings.TrimSuffix(stdout.String(), "\n"), "\n") {
+		titles = append(titles, strings.Split(line, "\t")[1])
+	}
+	got := strings.Join(titles, " ")
+	if want := "A100 A101 A102 A103 A104"; got != want {
+		t.Errorf("tail -f printed %s, want %s", got, want)
+	}
+
+	requests := stub.called("getHeadlines")
+	if last := requests[len(requests)-1].Req; last["since_id"] != 104.0 {
+		t.Errorf("tail -f: last poll asked for since_id %v, want 104",
+			last["since_id"])
+	}
+}
+
+func TestHeadAndTail(t *testing.T) {
+	// The feed holds articles 100 to 104; the server answers with the
+	// newest or oldest, as asked.
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "Blog"))),
+		"getHeadlines": func(req map[string]interface{}) interface{} {
+			ids := []int{104, 103, 102, 101, 100}
+			if req["order_by"] == "date_reverse" {
+				ids = []int{100, 101, 102, 103, 104}
+			}
+			if limit, ok := req["limit"].(float64); ok {
+				ids = ids[:int(limit)]
+			}
+			var headlines []interface{}
+			for _, id := range ids {
+				headlines = append(headlines,
+					headlineItem(id, 10, fmt.Sprint("A", id)))
+			}
+			return headlines
+		},
+	})
+
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"head", "-n", "2", "/News/Blog"}, "A104 A103"},
+		{[]string{"tail", "-n", "2", "/News/Blog"}, "A101 A100"},
+	}
+	for _, test := range tests {
+		stdout, stderr, code := runTool(t, stub, "", test.args...)
+		var titles []string
+		for _, line := range strings.Split(
+			strings.TrimSuffix(stdout, "\n"), "\n") {
+			titles = append(titles, strings.Split(line, "\t")[1])
+		}
+		got := strings.Join(titles, " ")
+		if code != EX_SUCCESS || got != test.want {
+			t.Errorf("%q: got exit %d, %s, stderr %q; want %s",
+				test.args, code, got, stderr, test.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import "os"
+
+// ioctlWidth is not supported on this platform; callers fall back on
+// $COLUMNS.
+func ioctlWidth(f *os.File) int {
+	return 0
+}
+
+// isTerminal reports whether f is attached to a terminal.
+// Without the terminal driver to ask, any character device will do.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,38 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+//go:build linux || darwin || freebsd || netbsd || openbsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// winsize asks the terminal driver for the size of f.
+// ok is false if f is not a terminal.
+func winsize(f *os.File) (cols int, ok bool) {
+	var ws struct {
+		Row, Col, Xpixel, Ypixel uint16
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(),
+		uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 {
+		return 0, false
+	}
+	return int(ws.Col), true
+}
+
+// ioctlWidth asks the terminal driver how many columns f has.
+// Returns 0 if f is not a terminal or the driver does not know.
+func ioctlWidth(f *os.File) int {
+	cols, _ := winsize(f)
+	return cols
+}
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	_, ok := winsize(f)
+	return ok
+}
@@ -0,0 +1,238 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"ttrss"
+)
+
+type Touch struct {
+	flHelp      bool
+	flRecurse   bool
+	flOlderThan ageValue
+	flIKnow     bool
+	flags       flag.FlagSet
+
+	// mode is the catchup mode --older-than asks for.
+	mode string
+
+	// feedsCaughtUp counts the feeds caught up, for the summary Run
+	// finishes with when given more than one to catch up.
+	feedsCaughtUp int
+}
+
+// catchupModes maps the ages --older-than accepts to the catchup modes that
+// the server has for them. It has no others.
+var catchupModes = map[time.Duration]string{
+	0:                   ttrss.CATCHUP_ALL,
+	24 * time.Hour:      ttrss.CATCHUP_1DAY,
+	7 * 24 * time.Hour:  ttrss.CATCHUP_1WEEK,
+	14 * 24 * time.Hour: ttrss.CATCHUP_2WEEK,
+}
+
+func (touch *Touch) Init() {
+	touch.flags.Init("touch", flag.PanicOnError)
+
+	touch.flags.BoolVar(&touch.flHelp, "h", false, "help")
+	touch.flags.BoolVar(&touch.flHelp, "help", false, "help")
+
+	recurseHelp := "mark everything in categories read"
+	touch.flags.BoolVar(&touch.flRecurse, "r", false, recurseHelp)
+	touch.flags.BoolVar(&touch.flRecurse, "R", false, recurseHelp)
+
+	touch.flags.Var(&touch.flOlderThan, "older-than",
+		"only mark articles older than `AGE` read: 1d, 1w, or 2w")
+	touch.flags.BoolVar(&touch.flIKnow, "i-know", false, iKnowUsage)
+}
+
+func (touch *Touch) Flags() *flag.FlagSet {
+	return &touch.flags
+}
+
+func (touch *Touch) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "touch [-r] [--older-than AGE] [--i-know] catpath... "+
+		"-- mark feeds read")
+}
+
+// Run marks every article in each feed named as read, as the web UI's
+// "mark as read" does.
+// It carries on past failures, and exits with the code for the last one:
+// EX_NOINPUT if nothing is at the path, EX_DATAERR if what is there is
+// a category and -r wasn't given, and EX_UNAVAILABLE if the server refused.
+// With -r, categories are caught up along with everything in them.
+// With --older-than, only articles older than that are marked read. The
+// server only knows a day, a week, and two weeks, so any other age is
+// a usage error. Servers too old to know any would mark everything read
+// instead, so for them, it gives up with EX_UNAVAILABLE before touching
+// anything.
+// Given more than one feed to catch up, by several catpaths or -r, it
+// finishes by saying how many feeds it caught up, and how many articles that
+// marked read.
+// A server whose address matches the dotfile's confirm_host_pattern needs
+// --i-know.
+func (touch *Touch) Run(args []string) {
+	touch.flags.Parse(args)
+
+	if touch.flHelp {
+		flagSetPrintUsage(touch.flags, os.Stdout, "touch")
+		exit(EX_SUCCESS)
+	}
+
+	if touch.flags.NArg() < 1 {
+		flagSetPrintUsage(touch.flags, os.Stderr, "touch")
+		exit(EX_USAGE)
+	}
+
+	mode, ok := catchupModes[time.Duration(touch.flOlderThan)]
+	if !ok {
+		fmt.Fprintf(os.Stderr,
+			"touch: --older-than can only be 1d, 1w, or 2w, not %s\n",
+			&touch.flOlderThan)
+		exit(EX_USAGE)
+	}
+	touch.mode = mode
+	confirmHostOrExit(touch.flIKnow)
+	if mode != ttrss.CATCHUP_ALL {
+		level, err := tt.GetApiLevel()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "touch:", err)
+			exit(EX_UNAVAILABLE)
+		}
+		if level < ttrss.API_LEVEL_CATCHUP_MODE {
+			fmt.Fprintf(os.Stderr, "touch: server API level %d is too "+
+				"old for --older-than (need %d): it would mark "+
+				"everything read\n", level, ttrss.API_LEVEL_CATCHUP_MODE)
+			exit(EX_UNAVAILABLE)
+		}
+	}
+
+	bulk := touch.flRecurse || touch.flags.NArg() > 1
+	var before ttrss.Counters
+	var countErr error
+	if bulk {
+		before, countErr = tt.GetCounters()
+	}
+
+	code := EX_SUCCESS
+	for _, catpath := range touch.flags.Args() {
+		item, err := ResolveCatPath(catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "touch:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		if itemCode := touch.touch(catpath, item); itemCode != EX_SUCCESS {
+			code = itemCode
+		}
+	}
+	if bulk {
+		touch.printSummary(before, countErr)
+	}
+	exit(code)
+}
+
+// printSummary says how many feeds were caught up, and, going by the unread
+// count before, how many articles that marked read. If the counts couldn't
+// be had, countErr says why, and only the feeds are counted.
+func (touch *Touch) printSummary(before ttrss.Counters, countErr error) {
+	summary := fmt.Sprintf("touch: caught up %d %s", touch.feedsCaughtUp,
+		plural(touch.feedsCaughtUp, "feed", "feeds"))
+	var after ttrss.Counters
+	if countErr == nil {
+		after, countErr = tt.GetCounters()
+	}
+	if countErr != nil {
+		verbosef("touch: can't count articles marked read: %v", countErr)
+		infof("%s", summary)
+		return
+	}
+	read := before.GlobalUnread - after.GlobalUnread
+	if read < 0 {
+		// New articles arrived meanwhile.
+		read = 0
+	}
+	infof("%s, marking %d %s read", summary, read,
+		plural(read, "article", "articles"))
+}
+
+// touch catches up item, found at catpath, and returns the exit code for how
+// that went.
+func (touch *Touch) touch(catpath string, item *ttrss.FeedTreeItem) int {
+	if item.Type == ttrss.Feed {
+		if err := catchup(catpath, item, touch.mode); err != nil {
+			fmt.Fprintf(os.Stderr, "touch: %s: %v\n", catpath, err)
+			return EX_UNAVAILABLE
+		}
+		touch.feedsCaughtUp++
+		return EX_SUCCESS
+	}
+
+	if !touch.flRecurse {
+		fmt.Fprintf(os.Stderr,
+			"touch: not a feed: %q is a category (use -r)\n", catpath)
+		return EX_DATAERR
+	}
+	// Special's feeds overlap everything else: catching up "All articles"
+	// would catch up the lot.
+	if item.IsVirtual() {
+		fmt.Fprintf(os.Stderr,
+			"touch: refusing to catch up %q: touch its feeds instead\n",
+			catpath)
+		return EX_DATAERR
+	}
+
+	// A category is only caught up one level deep, so catch up each below
+	// it in turn. The root isn't a category of its own; Uncategorized is
+	// found within it.
+	code := EX_SUCCESS
+	walkCatPath(item, catpath, func(cat *ttrss.FeedTreeItem,
+		catpath string) bool {
+		if cat.Type != ttrss.Category || cat.IsVirtual() {
+			return false
+		}
+		if cat.IsRoot() {
+			return true
+		}
+		if err := catchup(catpath, cat, touch.mode); err != nil {
+			fmt.Fprintf(os.Stderr, "touch: %s: %v\n", catpath, err)
+			code = EX_UNAVAILABLE
+			return true
+		}
+		for _, child := range cat.Items {
+			if child.Type == ttrss.Feed {
+				touch.feedsCaughtUp++
+			}
+		}
+		return true
+	})
+	return code
+}
+
+// catchup marks everything in item, found at catpath, as read, and logs the
+// change. Only the feeds directly in a category are caught up. mode is as
+// for CatchupFeed.
+func catchup(catpath string, item *ttrss.FeedTreeItem, mode string) error {
+	err := tt.CatchupFeed(item.ID, item.Type == ttrss.Category, mode)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	} else {
+		stats.affected++
+	}
+	logChange(ChangelogEntry{
+		Op: "touch", Path: catpath, ID: item.ID, Result: result})
+	return err
+}
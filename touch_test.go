@@ -0,0 +1,115 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"testing"
+	"ttrss"
+)
+
+// apiLevelOp answers getApiLevel with level.
+func apiLevelOp(level int) stubOp {
+	return func(map[string]interface{}) interface{} {
+		return map[string]interface{}{"level": level}
+	}
+}
+
+func TestTouchOlderThanNeedsCatchupMode(t *testing.T) {
+	tests := []struct {
+		name  string
+		level stubOp // nil if the server lacks getApiLevel
+		want  int
+	}{
+		{"current", apiLevelOp(ttrss.API_LEVEL_CATCHUP_MODE), EX_SUCCESS},
+		{"too old", apiLevelOp(ttrss.API_LEVEL_CATCHUP_MODE - 1),
+			EX_UNAVAILABLE},
+		{"older than getApiLevel", nil, EX_UNAVAILABLE},
+	}
+	for _, test := range tests {
+		ops := map[string]stubOp{
+			"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "A"))),
+			"catchupFeed": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{"status": "OK"}
+			},
+		}
+		if test.level != nil {
+			ops["getApiLevel"] = test.level
+		}
+		stub := newStubServer(t, ops)
+
+		_, stderr, code := runTool(t, stub, "", "touch", "--older-than",
+			"1w", "/News/A")
+		calls := stub.called("catchupFeed")
+		if test.want != EX_SUCCESS {
+			if code != test.want || len(calls) != 0 {
+				t.Errorf("%s: got exit %d, stderr %q, catchupFeed calls "+
+					"%+v; want exit %d and none", test.name, code, stderr,
+					calls, test.want)
+			}
+			continue
+		}
+		if code != EX_SUCCESS || len(calls) != 1 ||
+			calls[0].Req["mode"] != ttrss.CATCHUP_1WEEK {
+			t.Errorf("%s: got exit %d, stderr %q, catchupFeed calls %+v; "+
+				"want one with mode %s", test.name, code, stderr, calls,
+				ttrss.CATCHUP_1WEEK)
+		}
+	}
+
+	// Catching up everything needs no particular level.
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "A"))),
+		"catchupFeed": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"status": "OK"}
+		},
+	})
+	_, stderr, code := runTool(t, stub, "", "touch", "/News/A")
+	if code != EX_SUCCESS || len(stub.called("getApiLevel")) != 0 {
+		t.Errorf("touch without --older-than: got exit %d, stderr %q, "+
+			"getApiLevel calls %d; want exit 0 and none", code, stderr,
+			len(stub.called("getApiLevel")))
+	}
+}
+
+// unreadOp answers getCounters with each unread count in turn, as the
+// global count, then the last forever after.
+func unreadOp(unread ...int) stubOp {
+	return func(map[string]interface{}) interface{} {
+		count := unread[0]
+		if len(unread) > 1 {
+			unread = unread[1:]
+		}
+		return []interface{}{map[string]interface{}{
+			"id": "global-unread", "counter": count}}
+	}
+}
+
+func TestTouchSummary(t *testing.T) {
+	tree := treeOp(catItem(1, "News", feedItem(10, "A"), feedItem(11, "B"),
+		catItem(2, "More", feedItem(12, "C"))))
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"/News/A"}, ""},
+		{[]string{"/News/A", "/News/B"},
+			"touch: caught up 2 feeds, marking 7 articles read\n"},
+		{[]string{"-r", "/News"},
+			"touch: caught up 3 feeds, marking 7 articles read\n"},
+	}
+	for _, test := range tests {
+		stub := newStubServer(t, map[string]stubOp{
+			"getFeedTree": tree,
+			"getCounters": unreadOp(10, 3),
+			"catchupFeed": func(map[string]interface{}) interface{} {
+				return map[string]interface{}{"status": "OK"}
+			},
+		})
+		args := append([]string{"touch"}, test.args...)
+		_, stderr, code := runTool(t, stub, "", args...)
+		if code != EX_SUCCESS || stderr != test.want {
+			t.Errorf("%q: got exit %d, stderr %q; want %q", args, code,
+				stderr, test.want)
+		}
+	}
+}
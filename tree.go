@@ -0,0 +1,143 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ttrss"
+)
+
+type Tree struct {
+	flHelp     bool
+	flAll      bool
+	flDirsOnly bool
+	flLevel    int
+	flOutput   string
+	flags      flag.FlagSet
+
+	// Tallies for the closing report.
+	categories, feeds int
+}
+
+func (tree *Tree) Init() {
+	tree.flags.Init("tree", flag.PanicOnError)
+
+	tree.flags.BoolVar(&tree.flHelp, "h", false, "help")
+	tree.flags.BoolVar(&tree.flHelp, "help", false, "help")
+
+	tree.flags.BoolVar(&tree.flAll, "a", false,
+		"include the server's own categories and feeds, like Special")
+	dirsUsage := "list categories only"
+	tree.flags.BoolVar(&tree.flDirsOnly, "d", false, dirsUsage)
+	tree.flags.BoolVar(&tree.flDirsOnly, "dirs-only", false, dirsUsage)
+	tree.flags.IntVar(&tree.flLevel, "L", 0,
+		"descend at most `N` levels (default: no limit)")
+	tree.flags.StringVar(&tree.flOutput, "o", "", outputUsage)
+}
+
+func (tree *Tree) Flags() *flag.FlagSet {
+	return &tree.flags
+}
+
+func (tree *Tree) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "tree [-ad] [-L N] [-o FILE] [catpath...] "+
+		"-- draw the category hierarchy")
+}
+
+// Run draws the categories and feeds below each catpath (by default, /),
+// as tree(1) draws directories, and then counts them.
+// With -o, the drawing goes to a file instead of stdout.
+func (tree *Tree) Run(args []string) {
+	tree.flags.Parse(args)
+
+	if tree.flHelp {
+		flagSetPrintUsage(tree.flags, os.Stdout, "tree")
+		exit(EX_SUCCESS)
+	}
+
+	if tree.flLevel < 0 {
+		flagSetPrintUsage(tree.flags, os.Stderr, "tree")
+		exit(EX_USAGE)
+	}
+	outputTo(tree.flOutput)
+
+	catpaths := tree.flags.Args()
+	if len(catpaths) == 0 {
+		catpaths = []string{"/"}
+	}
+
+	code := EX_SUCCESS
+	for _, catpath := range catpaths {
+		item, err := ResolveCatPath(catpath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tree:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			var pathErr *PathError
+			if errors.As(err, &pathErr) {
+				code = EX_NOINPUT
+			}
+			continue
+		}
+
+		fmt.Println(display(catpath))
+		showVirtual := tree.flAll || item.IsVirtual()
+		tree.draw(item, "", 1, showVirtual)
+	}
+
+	fmt.Printf("\n%d %s", tree.categories,
+		plural(tree.categories, "category", "categories"))
+	if !tree.flDirsOnly {
+		fmt.Printf(", %d %s", tree.feeds, plural(tree.feeds, "feed", "feeds"))
+	}
+	fmt.Println()
+	exit(code)
+}
+
+// draw draws what's in cat, which is depth levels down, with each line
+// starting with prefix.
+func (tree *Tree) draw(cat *ttrss.FeedTreeItem, prefix string, depth int,
+	showVirtual bool) {
+	if tree.flLevel > 0 && depth > tree.flLevel {
+		return
+	}
+
+	var shown []*ttrss.FeedTreeItem
+	for i := range cat.Items {
+		item := &cat.Items[i]
+		if item.IsVirtual() && !showVirtual {
+			continue
+		}
+		if item.Type != ttrss.Category && tree.flDirsOnly {
+			continue
+		}
+		shown = append(shown, item)
+	}
+
+	for i, item := range shown {
+		branch, indent := "├── ", "│   "
+		if i == len(shown)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Println(prefix + branch + display(item.Name))
+
+		if item.Type == ttrss.Category {
+			tree.categories++
+			tree.draw(item, prefix+indent, depth+1, showVirtual)
+		} else {
+			tree.feeds++
+		}
+	}
+}
+
+// plural returns singular if n is 1, and otherwise, plural.
+func plural(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
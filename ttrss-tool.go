@@ -24,26 +24,41 @@ package main
 
 import (
 	"bufio"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net/http"
 	"os"
+	"os/exec"
 	"path"
-	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 	"ttrss"
 )
 
 // Exit Codes
 const (
-	EX_SUCCESS  = 0
-	EX_USAGE    = 64
-	EX_DATAERR  = 65
-	EX_PROTOCOL = 76
+	EX_SUCCESS     = 0
+	EX_USAGE       = 64
+	EX_DATAERR     = 65
+	EX_NOINPUT     = 66
+	EX_NOUSER      = 67
+	EX_UNAVAILABLE = 69
+	EX_CANTCREAT   = 73
+	EX_IOERR       = 74
+	EX_TEMPFAIL    = 75
+	EX_PROTOCOL    = 76
+	EX_NOPERM      = 77
+	EX_CONFIG      = 78
 )
 
 // General Flags
@@ -52,8 +67,20 @@ var (
 	flUser        string
 	flPass        string
 	flDotfilePath string
+	flStateDir    string
+	flMaxResponse int64
+	flMinTLS      string
+	flChangelog   string
+	flRawNames    bool
+	flVerbose     bool
+	flQuiet       bool
 )
 
+// confirmHost is set from the dotfile's confirm_host_pattern. Destructive
+// commands refuse to run against a server whose address it matches unless
+// given --i-know.
+var confirmHost *regexp.Regexp
+
 // tt is logged in by main() prior to running any command.
 var tt ttrss.Client
 
@@ -71,11 +98,50 @@ type Cmd interface {
 	// args contains the arguments to the subcommand (not including the
 	// subcommand name).
 	Run(args []string)
+
+	// Flags returns the subcommand's flags, as configured by Init.
+	Flags() *flag.FlagSet
+}
+
+// Commands whose names start with hiddenPrefix are for tools, not people,
+// and are left out of the usage message.
+const hiddenPrefix = "__"
+
+// LoginFree is implemented by commands that main() should run without
+// first loading the dotfile, checking the connection flags, and logging in.
+// Such commands are on their own.
+type LoginFree interface {
+	LoginFree()
 }
 
 var cmds = map[string]Cmd{
-	"ln": &Ln{},
-	"ls": &Ls{},
+	"__describe": &Describe{},
+	"cat":        &Cat{},
+	"clean":      &Clean{},
+	"config":     &Config{},
+	"du":         &Du{},
+	"find":       &Find{},
+	"grep":       &Grep{},
+	"head":       &Head{},
+	"label":      &Label{},
+	"ln":         &Ln{},
+	"ls":         &Ls{},
+	"mark":       &Mark{},
+	"mkdir":      &Mkdir{},
+	"mv":         &Mv{},
+	"publish":    &Publish{},
+	"refresh":    &Refresh{},
+	"rm":         &Rm{},
+	"rmdir":      &Rmdir{},
+	"score":      &Score{},
+	"search":     &Search{},
+	"star":       &Star{},
+	"stat":       &Stat{},
+	"tag":        &Tag{},
+	"tail":       &Tail{},
+	"touch":      &Touch{},
+	"tree":       &Tree{},
+	"url":        &URL{},
 }
 
 var userDefault = "admin"
@@ -94,11 +160,36 @@ func init() {
 	flag.StringVar(&flPass, "pass", noDefault, passwordHelp)
 	flag.StringVar(&flPass, "p", noDefault, passwordHelp)
 
+	flag.StringVar(&flStateDir, "state-dir", os.Getenv(toolHomeEnv),
+		"keep all files directly in `DIR` instead of the XDG directories"+
+			" (defaults to $"+toolHomeEnv+")")
+
 	dotfileDefault := xdgConfigSearch("ttrss-tool/config", false)
 	dotfileHelp :=
 		"dotfile path (defaults to $XDG_CONFIG_HOME/ttrss-tool/config"
 	flag.StringVar(&flDotfilePath, "dotfile", dotfileDefault, dotfileHelp)
 
+	flag.BoolVar(&flRawNames, "raw-names", false,
+		"print names as sent by the server, control characters and all")
+
+	verboseHelp := "log API calls, and summarize the command at exit"
+	flag.BoolVar(&flVerbose, "verbose", false, verboseHelp)
+	flag.BoolVar(&flVerbose, "v", false, verboseHelp)
+
+	quietHelp := "print only results and errors (overrides -v)"
+	flag.BoolVar(&flQuiet, "quiet", false, quietHelp)
+	flag.BoolVar(&flQuiet, "q", false, quietHelp)
+
+	flag.StringVar(&flChangelog, "changelog", noDefault,
+		"append a line of JSON to `FILE` for each change made")
+
+	flag.StringVar(&flMinTLS, "min-tls", noDefault,
+		"refuse TLS older than `VERSION` (1.0, 1.1, 1.2, or 1.3)")
+
+	flag.Int64Var(&flMaxResponse, "max-response-bytes",
+		ttrss.DefaultMaxResponseBytes,
+		"give up on API responses larger than this many bytes")
+
 	for _, cmd := range cmds {
 		cmd.Init()
 	}
@@ -111,7 +202,10 @@ func init() {
 			"Usage of %s: %s flags subcommand subflags subargs\n", name, name)
 		flag.PrintDefaults()
 		fmt.Fprintln(w, "Subcommands:")
-		for _, cmd := range cmds {
+		for name, cmd := range cmds {
+			if strings.HasPrefix(name, hiddenPrefix) {
+				continue
+			}
 			fmt.Fprint(w, "  ")
 			cmd.Synopsis(w)
 		}
@@ -120,10 +214,12 @@ func init() {
 
 func main() {
 	flag.Parse()
-
-	err := applyDotfile(flDotfilePath)
-	if err != nil {
-		log.Fatal(err.Error())
+	if flQuiet {
+		flVerbose = false
+	}
+	if !flagWasSet(flag.CommandLine, "dotfile") {
+		// The default was worked out before --state-dir was parsed.
+		flDotfilePath = xdgConfigSearch("ttrss-tool/config", false)
 	}
 
 	if flag.NArg() < 1 {
@@ -134,6 +230,52 @@ func main() {
 		os.Exit(EX_USAGE)
 	}
 
+	requestedName := flag.Arg(0)
+	chosenCmd := cmds[requestedName]
+	if chosenCmd == nil {
+		availableCommands := make([]string, 0, len(cmds))
+		for name := range cmds {
+			if !strings.HasPrefix(name, hiddenPrefix) {
+				availableCommands = append(availableCommands, name)
+			}
+		}
+		sort.Strings(availableCommands)
+
+		fmt.Fprintf(os.Stderr,
+			"%s: error: unknown command %q: expected one of %v\n",
+			os.Args[0], requestedName, availableCommands)
+		os.Exit(EX_USAGE)
+	}
+
+	runningCmd = requestedName
+	tt.OnRequest = func(op string, body map[string]interface{}) {
+		stats.callsMu.Lock()
+		stats.calls++
+		stats.callsMu.Unlock()
+		verbosef("-> %s", op)
+	}
+	tt.OnResponse = func(op string, body map[string]interface{},
+		resp ttrss.Resp, err error) {
+		switch {
+		case err != nil:
+			verbosef("<- %s: %v", op, err)
+		case resp.Error != nil:
+			verbosef("<- %s: status %d: %v", op, resp.Status, resp.Error)
+		default:
+			verbosef("<- %s: status %d", op, resp.Status)
+		}
+	}
+
+	if _, ok := chosenCmd.(LoginFree); ok {
+		chosenCmd.Run(flag.Args()[1:])
+		exit(EX_SUCCESS)
+	}
+
+	err := applyDotfile(flDotfilePath)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+
 	if !strings.HasPrefix(flAddr, "http") {
 		fmt.Fprintf(os.Stderr,
 			"%s: error: address %q must start with \"http\"\n",
@@ -142,139 +284,238 @@ func main() {
 	}
 
 	if flPass == "" {
-		flPass, err = readPassword(os.Stdin, os.Stdout)
+		flPass, err = readPassword(os.Stdin, os.Stdout, "password")
 		if err != nil {
 			log.Fatal(err.Error())
 		}
 	}
 
-	requestedName := flag.Arg(0)
-	chosenCmd := cmds[requestedName]
-	if chosenCmd == nil {
-		availableCommands := make([]string, len(cmds))
-		for name := range cmds {
-			availableCommands = append(availableCommands, name)
-		}
-		sort.Strings(availableCommands)
-
-		fmt.Fprintf(os.Stderr,
-			"%s: error: unknown command %q: expected one of %v\n",
-			os.Args[0], requestedName, availableCommands)
+	if err = configureClient(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: error: %v\n", os.Args[0], err)
 		os.Exit(EX_USAGE)
 	}
-
-	tt.Login(ttrss.ConnInfo{flAddr, flUser, flPass})
+	_, err = tt.Login(ttrss.ConnInfo{
+		HostURL: flAddr, User: flUser, Password: flPass})
+	if err != nil {
+		log.Fatalln(err)
+	}
 
 	chosenCmd.Run(flag.Args()[1:])
+	exit(EX_SUCCESS)
 }
 
-func flagSetPrintUsage(fl flag.FlagSet, w io.Writer, progname string) {
-	fmt.Fprintf(w, "Usage of %s:\n", progname)
-	fl.SetOutput(w)
-	fl.PrintDefaults()
-}
+// runningCmd names the subcommand main() chose to run.
+var runningCmd string
 
-type Ln struct {
-	flHelp bool
-	flags  flag.FlagSet
-}
+// stats is summarized by exit() under --verbose.
+var stats struct {
+	start time.Time
 
-func (ln *Ln) Init() {
-	ln.flags.Init("ln", flag.PanicOnError)
+	// calls is counted as requests go out, which they can do several at
+	// once, as under ln --jobs: callsMu guards it.
+	callsMu sync.Mutex
+	calls   int
 
-	ln.flags.BoolVar(&ln.flHelp, "h", false, "help")
-	ln.flags.BoolVar(&ln.flHelp, "help", false, "help")
+	// affected counts feeds, categories, or articles changed by the command.
+	affected int
 }
 
-func (ln *Ln) Synopsis(w io.Writer) {
-	fmt.Println("ln feed [catpath] -- subscribe to a new feed")
+func init() {
+	stats.start = time.Now()
 }
 
-func (ln *Ln) Run(args []string) {
-	ln.flags.Parse(args)
-
-	if ln.flHelp {
-		flagSetPrintUsage(ln.flags, os.Stdout, "ln")
-		os.Exit(EX_SUCCESS)
+// exit ends the program, summarizing what happened first if --verbose.
+// Commands should use this rather than os.Exit.
+func exit(code int) {
+	if output != nil {
+		if err := output.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", runningCmd, err)
+			if code == EX_SUCCESS {
+				code = EX_IOERR
+			}
+		}
 	}
-
-	argc := ln.flags.NArg()
-	if argc < 1 {
-		flagSetPrintUsage(ln.flags, os.Stderr, "ln")
-		os.Exit(EX_USAGE)
+	if flVerbose {
+		fmt.Fprintf(os.Stderr,
+			"%s: %s: %.3fs elapsed, %d API calls, %d affected, exit %d\n",
+			os.Args[0], runningCmd, time.Since(stats.start).Seconds(),
+			stats.calls, stats.affected, code)
 	}
+	os.Exit(code)
+}
+
+// outputUsage describes -o to the commands that take it.
+const outputUsage = "write output to `FILE` rather than stdout"
 
-	feed := ln.flags.Arg(0)
-	catpath := ln.flags.Arg(1)
-	item, err := ResolveCatPath(catpath)
+// output is the file given by -o, if any, closed by exit.
+var output *os.File
+
+// outputTo sends what the command goes on to print to stdout to the file at
+// path instead, creating it or truncating it first. Diagnostics stay on
+// stderr. An empty path leaves stdout be. If the file can't be written, it
+// says so and exits EX_CANTCREAT.
+func outputTo(path string) {
+	if path == "" {
+		return
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
-		log.Fatalln(err)
+		fmt.Fprintf(os.Stderr, "%s: can't write output: %v\n", runningCmd,
+			err)
+		exit(EX_CANTCREAT)
 	}
+	output = file
+	os.Stdout = file
+}
 
-	if item.Type != ttrss.Category {
-		log.Fatalln("error: not a category:", catpath)
+// verbosef writes a line to stderr under --verbose.
+func verbosef(format string, args ...interface{}) {
+	if flVerbose {
+		fmt.Fprintf(os.Stderr, "### "+format+"\n", args...)
 	}
+}
 
-	subscribed, err := tt.Subscribe(feed, item.ID, "", "")
+// infof writes a line to stderr unless --quiet.
+func infof(format string, args ...interface{}) {
+	if !flQuiet {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
 
-	if s, ok := err.(*ttrss.SubscribeError); ok {
-		if (s.Status != ttrss.SUB_ADDED) {
-			fmt.Fprintln(os.Stderr, s.Message)
+// inParallel calls work(i) for each i from 0 to n-1, up to jobs at a time,
+// and report(i) for each in turn, once work(i) is done.
+// Only work runs in other goroutines, so report can safely print, prompt, and
+// keep count, while work should stick to calling the server.
+func inParallel(n, jobs int, work, report func(i int)) {
+	done := make([]chan struct{}, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	go func() {
+		slots := make(chan struct{}, jobs)
+		for i := 0; i < n; i++ {
+			slots <- struct{}{}
+			go func(i int) {
+				work(i)
+				<-slots
+				close(done[i])
+			}(i)
 		}
-	}
+	}()
 
-	if subscribed {
-		os.Exit(EX_SUCCESS)
+	for i := 0; i < n; i++ {
+		<-done[i]
+		report(i)
 	}
-	os.Exit(EX_DATAERR)
 }
 
-type Ls struct {
-	flHelp    bool
-	flRecurse bool
-	flags     flag.FlagSet
+// tlsVersions maps --min-tls values to tls.Config versions.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
 }
 
-func (ls *Ls) Init() {
-	ls.flags.Init("ls", flag.PanicOnError)
+// configureClient applies the connection flags to tt.
+func configureClient() error {
+	tt.MaxResponseBytes = flMaxResponse
 
-	ls.flags.BoolVar(&ls.flHelp, "h", false, "help")
-	ls.flags.BoolVar(&ls.flHelp, "help", false, "help")
-
-	recurseUsage := "recurse into categories"
-	ls.flags.BoolVar(&ls.flRecurse, "R", false, recurseUsage)
-	ls.flags.BoolVar(&ls.flRecurse, "Recurse", false, recurseUsage)
+	if flMinTLS != "" {
+		version, ok := tlsVersions[flMinTLS]
+		if !ok {
+			return fmt.Errorf("unknown TLS version %q: expected one of "+
+				"1.0, 1.1, 1.2, 1.3", flMinTLS)
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = &tls.Config{MinVersion: version}
+		tt.Client.Transport = transport
+	}
+	return nil
 }
 
-func (ls *Ls) Synopsis(w io.Writer) {
-	fmt.Fprintln(w, "ls [-R] [catpath...] -- list categories and feeds")
+func flagSetPrintUsage(fl flag.FlagSet, w io.Writer, progname string) {
+	fmt.Fprintf(w, "Usage of %s:\n", progname)
+	fl.SetOutput(w)
+	fl.PrintDefaults()
 }
 
-func (ls *Ls) Run(args []string) {
-	fmt.Println("### parsing `ls` args")
-	_ = ls.flags.Parse(args)
-	if ls.flHelp {
-		flagSetPrintUsage(ls.flags, os.Stdout, "ls")
-		return
+// terminalWidth returns the width of the terminal attached to f.
+// It falls back on $COLUMNS, and failing that, on 80 columns.
+func terminalWidth(f *os.File) int {
+	if width := ioctlWidth(f); width > 0 {
+		return width
 	}
-	fmt.Printf("### parsed: %#v\n", ls)
-
-	catpath := "/"
-	if len(args) > 0 {
-		catpath = args[0]
+	if width, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil &&
+		width > 0 {
+		return width
 	}
+	return 80
+}
 
-	root, err := ResolveCatPath(catpath)
-	if err != nil {
-		log.Fatalf("unable to list %q: %v", catpath, err)
+// flagWasSet reports whether the flag name was given on the command line.
+func flagWasSet(fs *flag.FlagSet, name string) (set bool) {
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return
+}
+
+// ageValue is a flag.Value for how old something is: a time.Duration, like
+// "36h", or a whole number of days or weeks, like "2d" or "1w".
+type ageValue time.Duration
+
+func (age *ageValue) String() string {
+	return time.Duration(*age).String()
+}
+
+func (age *ageValue) Set(text string) error {
+	if text == "" {
+		return errors.New("empty age")
+	}
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	if unit, ok := units[text[len(text)-1:]]; ok && len(text) > 1 {
+		n, err := strconv.Atoi(text[:len(text)-1])
+		if err != nil || n < 0 {
+			return fmt.Errorf("bad age %q", text)
+		}
+		*age = ageValue(time.Duration(n) * unit)
+		return nil
 	}
 
-	for _, item := range root.Items {
-		fmt.Println(item.Name)
+	d, err := time.ParseDuration(text)
+	if err != nil || d < 0 {
+		return fmt.Errorf("bad age %q", text)
 	}
+	*age = ageValue(d)
+	return nil
 }
 
+// toolHomeEnv names the environment variable that does the same job as
+// --state-dir.
+const toolHomeEnv = "TTRSS_TOOL_HOME"
+
+// xdgConfigSearch returns the path to the config file subpath, like
+// "ttrss-tool/config", in the first of the XDG config directories to have it.
+// If none do, it returns the path the file would have in the first of them
+// to exist, unless onlyIfExists.
+//
+// If --state-dir or $TTRSS_TOOL_HOME gives a directory, that's the only place
+// looked, and the file is expected directly within it: "DIR/config".
 func xdgConfigSearch(subpath string, onlyIfExists bool) (filePath string) {
+	if flStateDir != "" {
+		filePath = path.Join(flStateDir, path.Base(subpath))
+		if _, err := os.Stat(filePath); err != nil && onlyIfExists {
+			filePath = ""
+		}
+		return
+	}
+
 	home := os.Getenv("HOME")
 	dir := os.Getenv("XDG_CONFIG_HOME")
 	if dir == "" {
@@ -331,6 +572,8 @@ func applyDotfile(path string) (err error) {
 		Addr string
 		User string
 		Pass string
+
+		ConfirmHostPattern string `json:"confirm_host_pattern"`
 	}
 	var config Config
 	err = json.Unmarshal(bytes, &config)
@@ -350,15 +593,41 @@ func applyDotfile(path string) (err error) {
 	if flPass == "" {
 		flPass = config.Pass
 	}
+	if config.ConfirmHostPattern != "" {
+		confirmHost, err = regexp.Compile(config.ConfirmHostPattern)
+		if err != nil {
+			err = fmt.Errorf("error: bad confirm_host_pattern in dotfile "+
+				"[%s]: %s", path, err)
+			return
+		}
+	}
 	return
 }
 
-// Reads a password from r after writing prompt to w.
-func readPassword(r io.Reader, w io.Writer) (pass string, err error) {
+// iKnowUsage is the usage of destructive commands' --i-know flag.
+const iKnowUsage = "go ahead even if the address matches the dotfile's " +
+	"confirm_host_pattern"
+
+// confirmHostOrExit exits EX_NOPERM, saying why, if the server's address
+// matches the dotfile's confirm_host_pattern, unless iKnow is set.
+// Destructive commands call this before changing anything.
+func confirmHostOrExit(iKnow bool) {
+	if confirmHost == nil || iKnow || !confirmHost.MatchString(flAddr) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "%s: %s matches confirm_host_pattern %q: "+
+		"give --i-know to go ahead\n", runningCmd, flAddr, confirmHost)
+	exit(EX_NOPERM)
+}
+
+// Reads a password from r after writing a prompt for it to w, naming it
+// what, as in "password".
+func readPassword(r io.Reader, w io.Writer, what string) (
+	pass string, err error) {
 	scanner := bufio.NewScanner(r)
 
 	for {
-		fmt.Fprint(w, "password (will be echoed): ")
+		fmt.Fprintf(w, "%s (will be echoed): ", what)
 		ok := scanner.Scan()
 		if !ok {
 			msg := "error: failed reading password"
@@ -375,6 +644,23 @@ func readPassword(r io.Reader, w io.Writer) (pass string, err error) {
 	}
 }
 
+// Runs command with the shell and returns what it prints, less the trailing
+// newline, as a password, so that one can be kept in a keychain or password
+// manager rather than on the command line.
+func passwordFromCommand(command string) (pass string, err error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	out, err := cmd.Output()
+	if err != nil {
+		err = fmt.Errorf("error: running %q: %v", command, err)
+		return
+	}
+	if pass = strings.TrimSuffix(string(out), "\n"); pass == "" {
+		err = fmt.Errorf("error: %q printed no password", command)
+	}
+	return
+}
+
 func PathComponents(path string) (parts []string) {
 	// Trim initial slash; "/" is treated the same as "".
 	if strings.HasPrefix(path, "/") {
@@ -383,7 +669,6 @@ func PathComponents(path string) (parts []string) {
 
 	// Split into rough parts. This does NOT respect backslash escapes.
 	roughParts := strings.Split(path, "/")
-	fmt.Println(path, "=> roughly", roughParts)
 
 	// Now clean up rough parts to get the various levels.
 	partial := ""
@@ -403,54 +688,299 @@ func PathComponents(path string) (parts []string) {
 			partial = ""
 		}
 	}
-	fmt.Println(path, "=>", parts)
 	return
 }
 
-type catPathResult struct {
-	item *ttrss.FeedTreeItem
+// ResolveCatPath finds the category or feed named by catpath.
+// The root of the tree is "/", as is the empty catpath.
+//
+// A feed can share its name with a sibling feed or category. A trailing slash
+// on catpath rules out feeds; if that's not enough, the user is asked to pick
+// when on a terminal, and otherwise an *AmbiguousPathError is returned.
+func ResolveCatPath(catpath string) (item *ttrss.FeedTreeItem, err error) {
+	tree, err := tt.GetFeedTree(true)
+	if err != nil {
+		return
+	}
+	return resolveCatPathIn(&tree, catpath)
 }
 
-func (err *catPathResult) Error() string {
-	return ""
+// resolveCatPathIn is ResolveCatPath for a tree already fetched, for
+// commands resolving several catpaths at once.
+func resolveCatPathIn(tree *ttrss.FeedTreeItem, catpath string) (
+	item *ttrss.FeedTreeItem, err error) {
+	verbosef("resolving %q", catpath)
+	parts := PathComponents(catpath)
+	wantCategory := strings.HasSuffix(catpath, "/") &&
+		!strings.HasSuffix(catpath, "\\/")
+	item = tree
+	for i, part := range parts {
+		var candidates []*ttrss.FeedTreeItem
+		if item.Type == ttrss.Category {
+			// Only the last part can name a feed.
+			onlyCategories := wantCategory || i < len(parts)-1
+			candidates = findChildren(item, part, onlyCategories)
+		}
+
+		switch len(candidates) {
+		case 0:
+			err = newPathError(catpath, part, item)
+			return
+		case 1:
+			item = candidates[0]
+		default:
+			ambiguity := &AmbiguousPathError{catpath, candidates}
+			if !isTerminal(os.Stdin) {
+				err = ambiguity
+				return
+			}
+			item, err = pickCandidate(os.Stdin, os.Stderr, ambiguity)
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
 }
 
-func ResolveCatPath(catpath string) (item *ttrss.FeedTreeItem, err error) {
-	fmt.Println("### resolving", catpath)
+// splitCatPath splits catpath into the catpath of its parent and the name of
+// its last component, unescaped. The parent of a top-level item is "/".
+func splitCatPath(catpath string) (parentPath, name string) {
 	parts := PathComponents(catpath)
-	tree, err := tt.GetFeedTree(true)
-	if err != nil {
+	if len(parts) == 0 {
+		return "/", ""
+	}
+	parentPath = "/"
+	for _, part := range parts[:len(parts)-1] {
+		parentPath += ttrss.EscapePathComponent(part) + "/"
+	}
+	return parentPath, parts[len(parts)-1]
+}
+
+// asCategoryPath returns catpath with a trailing slash, so that it can only
+// resolve to a category.
+func asCategoryPath(catpath string) string {
+	if strings.HasSuffix(catpath, "/") && !strings.HasSuffix(catpath, "\\/") {
+		return catpath
+	}
+	return catpath + "/"
+}
+
+// PathError reports that no item goes by Path.
+type PathError struct {
+	Path string
+
+	// FailedComponent is the first component of Path that could not be
+	// found.
+	FailedComponent string
+
+	// Candidates names what was found in its place: the items in the
+	// category where FailedComponent was expected, or nothing, if that was
+	// a feed.
+	Candidates []string
+}
+
+func (err *PathError) Error() string {
+	return fmt.Sprintf("not found: %q: nothing named %q",
+		err.Path, err.FailedComponent)
+}
+
+// newPathError reports that component could not be found in parent.
+func newPathError(path, component string, parent *ttrss.FeedTreeItem) (
+	err *PathError) {
+	err = &PathError{Path: path, FailedComponent: component}
+	if parent.Type == ttrss.Category {
+		for _, item := range parent.Items {
+			err.Candidates = append(err.Candidates, item.Name)
+		}
+	}
+	return
+}
+
+// printCandidates tells the user what was there instead when err is (or
+// wraps) a *PathError, as long as that's not an overwhelming list.
+func printCandidates(err error) {
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) || len(pathErr.Candidates) == 0 {
+		return
+	}
+
+	const tooMany = 20
+	if len(pathErr.Candidates) > tooMany {
+		infof("(%d items there, none named %q)",
+			len(pathErr.Candidates), pathErr.FailedComponent)
+		return
+	}
+	names := make([]string, len(pathErr.Candidates))
+	for i, name := range pathErr.Candidates {
+		names[i] = display(name)
+	}
+	infof("(there instead: %s)", strings.Join(names, ", "))
+}
+
+// AmbiguousPathError reports that more than one item goes by Path.
+type AmbiguousPathError struct {
+	Path       string
+	Candidates []*ttrss.FeedTreeItem
+}
+
+func (err *AmbiguousPathError) Error() string {
+	text := fmt.Sprintf("ambiguous: %q could be any of:", err.Path)
+	for _, item := range err.Candidates {
+		text += " " + describeItem(item)
+	}
+	return text
+}
+
+// describeItem identifies item in the API's own style, like "FEED:12".
+func describeItem(item *ttrss.FeedTreeItem) string {
+	if item.Type == ttrss.Category {
+		return fmt.Sprintf("CAT:%d", item.ID)
+	}
+	return fmt.Sprintf("FEED:%d", item.ID)
+}
+
+// pickCandidate asks the user on w to pick one of ambiguity's candidates by
+// number, and reads the answer from r.
+func pickCandidate(r io.Reader, w io.Writer, ambiguity *AmbiguousPathError) (
+	item *ttrss.FeedTreeItem, err error) {
+	fmt.Fprintf(w, "%q could be any of:\n", ambiguity.Path)
+	for i, candidate := range ambiguity.Candidates {
+		fmt.Fprintf(w, "  %d) %-10s %s %s\n", i+1,
+			describeItem(candidate), candidate.Type, display(candidate.Name))
+	}
+
+	scanner := bufio.NewScanner(r)
+	for {
+		fmt.Fprintf(w, "which one? [1-%d] ", len(ambiguity.Candidates))
+		if !scanner.Scan() {
+			err = ambiguity
+			return
+		}
+		choice, convErr := strconv.Atoi(strings.TrimSpace(scanner.Text()))
+		if convErr == nil && 1 <= choice &&
+			choice <= len(ambiguity.Candidates) {
+			item = ambiguity.Candidates[choice-1]
+			return
+		}
+	}
+}
+
+// confirm asks prompt on w, and reports whether the answer read from answers
+// was yes. Anything else, including no answer at all, is no.
+func confirm(answers *bufio.Scanner, w io.Writer, prompt string) bool {
+	fmt.Fprintf(w, "%s [y/N] ", prompt)
+	if !answers.Scan() {
+		fmt.Fprintln(w)
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(answers.Text()))
+	return answer == "y" || answer == "yes"
+}
+
+// ResolveArticlePath is like ResolveCatPath, but also looks inside feeds:
+// "/News/Feed/1234" names article 1234 in Feed.
+// If path names an article, it is returned along with its feed.
+// Otherwise, article is nil and item is as ResolveCatPath would find it.
+func ResolveArticlePath(path string) (item *ttrss.FeedTreeItem,
+	article *ttrss.Headline, err error) {
+	item, err = ResolveCatPath(path)
+	var pathErr *PathError
+	if !errors.As(err, &pathErr) {
 		return
 	}
 
-	walkParts := parts
+	// Only the last component can name an article.
+	parts := PathComponents(path)
+	last := parts[len(parts)-1]
+	articleID, convErr := strconv.Atoi(last)
+	if convErr != nil {
+		return
+	}
 
-	/* Gradually eat walkParts till there are none left.
-	 * At that point, we've reached our category. */
-	walkFn := func(item *ttrss.FeedTreeItem) error {
-		fmt.Println("walk:", item.Name, item.Type, item.ID, "-", walkParts)
-		isCat := item.Type == ttrss.Category
-		if len(walkParts) == 0 {
-			return &catPathResult{item}
+	dir := ""
+	for _, part := range parts[:len(parts)-1] {
+		dir += "/" + ttrss.EscapePathComponent(part)
+	}
+	feed, feedErr := ResolveCatPath(dir)
+	if feedErr != nil || feed.Type != ttrss.Feed {
+		return
+	}
+
+	// Only now that we know we're inside a feed do we ask after its articles.
+	req := headlinesRequestFor(feed)
+	req.SinceID = articleID - 1
+	req.OrderBy = ttrss.ORDER_DATE_REVERSE
+	headlines, err := tt.GetHeadlines(req)
+	if err != nil {
+		return
+	}
+	for i := range headlines {
+		if headlines[i].ID == articleID {
+			item, article = feed, &headlines[i]
+			return
 		}
+	}
+	err = &PathError{Path: path, FailedComponent: last}
+	return
+}
 
-		if item.Name == walkParts[0] {
-			walkParts = walkParts[1:len(walkParts)-1]
-			return nil
+// ResolvePrefix returns the catpaths that could follow from the partial
+// catpath prefix, sorted by name. For example, "/News/T" might yield
+// "/News/Tech" and "/News/Travel", and "/News/" everything in News.
+// This is the engine for shell completion.
+func ResolvePrefix(prefix string) (paths []string, err error) {
+	parts := PathComponents(prefix)
+
+	// Everything but the last part names the category to look in,
+	// unless the prefix ends in an unescaped slash.
+	partial := ""
+	endsInSlash := strings.HasSuffix(prefix, "/") &&
+		!strings.HasSuffix(prefix, "\\/")
+	if len(parts) > 0 && !endsInSlash {
+		partial = parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+	}
+
+	tree, err := tt.GetFeedTree(true)
+	if err != nil {
+		return
+	}
+
+	cat := &tree
+	dir := "/"
+	for _, part := range parts {
+		// A feed may share its name with the category we're after.
+		children := findChildren(cat, part, true)
+		if len(children) == 0 {
+			err = newPathError(prefix, part, cat)
+			return
 		}
+		cat = children[0]
+		dir += ttrss.EscapePathComponent(part) + "/"
+	}
 
-		if isCat {
-			return filepath.SkipDir
+	for _, item := range cat.Items {
+		if strings.HasPrefix(item.Name, partial) {
+			paths = append(paths, dir+ttrss.EscapePathComponent(item.Name))
 		}
-		return nil
 	}
+	sort.Strings(paths)
+	return
+}
 
-	err = ttrss.WalkFeedTree(&tree, walkFn)
-	result, ok := err.(*catPathResult)
-	if ok {
-		item = result.item
-		err = nil
+// findChildren returns the items named name directly within cat.
+func findChildren(cat *ttrss.FeedTreeItem, name string, onlyCategories bool) (
+	children []*ttrss.FeedTreeItem) {
+	for i := range cat.Items {
+		child := &cat.Items[i]
+		if child.Name != name {
+			continue
+		}
+		if onlyCategories && child.Type != ttrss.Category {
+			continue
+		}
+		children = append(children, child)
 	}
-	err = fmt.Errorf("not found: %q", catpath)
 	return
 }
@@ -0,0 +1,471 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ttrss"
+)
+
+// prefixTree has a feed named Tech ahead of the category named Tech.
+var prefixTree = treeOp(
+	catItem(1, "News",
+		feedItem(13, "Tech"),
+		catItem(2, "Tech", feedItem(10, "Go Blog")),
+		catItem(3, "Travel"),
+		feedItem(12, "World")),
+	catItem(4, "a/b"))
+
+func TestResolvePrefix(t *testing.T) {
+	useStub(t, newStubServer(t, map[string]stubOp{
+		"getFeedTree": prefixTree}))
+
+	tests := []struct {
+		prefix string
+		want   []string
+	}{
+		{"", []string{"/News", `/a\/b`}},
+		{"/", []string{"/News", `/a\/b`}},
+		{"/N", []string{"/News"}},
+		{"/News/", []string{
+			"/News/Tech", "/News/Tech", "/News/Travel", "/News/World"}},
+		{"/News/T", []string{"/News/Tech", "/News/Tech", "/News/Travel"}},
+		{"/News/Tech", []string{"/News/Tech", "/News/Tech"}},
+		{"/News/Tech/", []string{"/News/Tech/Go Blog"}},
+		{"/News/Tech/G", []string{"/News/Tech/Go Blog"}},
+		{"/News/X", nil},
+		{`/a\/b/`, nil},
+	}
+	for _, test := range tests {
+		got, err := ResolvePrefix(test.prefix)
+		if err != nil || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ResolvePrefix(%q) = %q, %v; want %q", test.prefix,
+				got, err, test.want)
+		}
+	}
+}
+
+func TestResolvePrefixErrors(t *testing.T) {
+	useStub(t, newStubServer(t, map[string]stubOp{
+		"getFeedTree": prefixTree}))
+
+	tests := []struct {
+		prefix string
+		failed string
+	}{
+		{"/Nope/", "Nope"},
+		{"/News/World/", "World"},
+		{"/News/Nope/G", "Nope"},
+	}
+	for _, test := range tests {
+		got, err := ResolvePrefix(test.prefix)
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) ||
+			pathErr.FailedComponent != test.failed {
+			t.Errorf("ResolvePrefix(%q) = %q, %v; want a PathError at %q",
+				test.prefix, got, err, test.failed)
+		}
+	}
+}
+
+func TestFindChildren(t *testing.T) {
+	useStub(t, newStubServer(t, map[string]stubOp{
+		"getFeedTree": prefixTree}))
+	root, err := tt.GetFeedTree(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	news := &root.Items[0]
+
+	tests := []struct {
+		name           string
+		onlyCategories bool
+		want           []int
+	}{
+		{"Tech", false, []int{13, 2}},
+		{"Tech", true, []int{2}},
+		{"World", false, []int{12}},
+		{"World", true, nil},
+		{"Nope", false, nil},
+		{"tech", false, nil},
+	}
+	for _, test := range tests {
+		var got []int
+		for _, child := range findChildren(news, test.name,
+			test.onlyCategories) {
+			got = append(got, child.ID)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("findChildren(News, %q, %v) = IDs %v, want %v",
+				test.name, test.onlyCategories, got, test.want)
+		}
+	}
+}
+
+func TestEscapePathComponent(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"plain", "plain"},
+		{"a/b", `a\/b`},
+		{"/lead", `\/lead`},
+		{"trail/", `trail\/`},
+		{"a//b", `a\/\/b`},
+	}
+	for _, test := range tests {
+		got := ttrss.EscapePathComponent(test.name)
+		if got != test.want {
+			t.Errorf("EscapePathComponent(%q) = %q, want %q",
+				test.name, got, test.want)
+		}
+		// Escaped, it's read back as a single component.
+		parts := PathComponents("/News/" + got)
+		if want := []string{"News", test.name}; !reflect.DeepEqual(
+			parts, want) {
+			t.Errorf("PathComponents(%q) = %q, want %q",
+				"/News/"+got, parts, want)
+		}
+	}
+}
+
+func TestVerboseSummary(t *testing.T) {
+	stub := newStubServer(t, map[string]stubOp{
+		"getFeedTree": treeOp(catItem(1, "News")),
+		"addCategory": func(map[string]interface{}) interface{} {
+			return map[string]interface{}{"category_id": 7}
+		},
+	})
+	summary := regexp.MustCompile(`^ttrss-tool: mkdir: [0-9.]+s elapsed, ` +
+		`3 API calls, 1 affected, exit 0$`)
+
+	tests := []struct {
+		flags []string
+		want  bool
+	}{
+		{nil, false},
+		{[]string{"-v"}, true},
+		{[]string{"--verbose"}, true},
+		{[]string{"-q"}, false},
+		{[]string{"-v", "-q"}, false},
+	}
+	for _, test := range tests {
+		args := append(test.flags, "mkdir", "/News/Go")
+		_, stderr, code := runTool(t, stub, "", args...)
+		lines := strings.Split(strings.TrimSuffix(stderr, "\n"), "\n")
+		got := summary.MatchString(lines[len(lines)-1])
+		if code != EX_SUCCESS || got != test.want {
+			t.Errorf("%q: got exit %d, stderr %q; want a summary: %v",
+				args, code, stderr, test.want)
+		}
+	}
+}
+
+// dupTree has names shared by siblings.
+var dupTree = treeOp(
+	catItem(1, "News",
+		feedItem(10, "Dup"),
+		feedItem(11, "Dup"),
+		catItem(2, "Both"),
+		feedItem(12, "Both")))
+
+func TestResolveCatPathAmbiguous(t *testing.T) {
+	useStub(t, newStubServer(t, map[string]stubOp{"getFeedTree": dupTree}))
+	saved := os.Stdin
+	defer func() { os.Stdin = saved }()
+	var err error
+	if os.Stdin, err = os.Open(os.DevNull); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Stdin.Close()
+
+	tests := []struct {
+		catpath string
+		want    string // the item found, or else the candidates
+	}{
+		{"/News/Dup", "FEED:10 FEED:11"},
+		{"/News/Both", "CAT:2 FEED:12"},
+		{"/News/Both/", "CAT:2"},
+	}
+	for _, test := range tests {
+		item, err := ResolveCatPath(test.catpath)
+		var got []string
+		var ambiguity *AmbiguousPathError
+		switch {
+		case errors.As(err, &ambiguity):
+			for _, candidate := range ambiguity.Candidates {
+				got = append(got, describeItem(candidate))
+			}
+		case err != nil:
+			got = []string{err.Error()}
+		default:
+			got = []string{describeItem(item)}
+		}
+		if strings.Join(got, " ") != test.want {
+			t.Errorf("ResolveCatPath(%q) = %q, want %s", test.catpath,
+				got, test.want)
+		}
+	}
+}
+
+func TestPickCandidate(t *testing.T) {
+	ambiguity := &AmbiguousPathError{"/News/Both", []*ttrss.FeedTreeItem{
+		{ID: 2, Name: "Both", Type: ttrss.Category},
+		{ID: 12, Name: "Both", Type: ttrss.Feed},
+	}}
+	tests := []struct {
+		answers string
+		want    int // the ID picked, or 0 if none
+		prompts int
+	}{
+		{"1\n", 2, 1},
+		{" 2 \n", 12, 1},
+		{"x\n0\n3\n2\n", 12, 4},
+		{"", 0, 1},
+		{"x\n", 0, 2},
+	}
+	for _, test := range tests {
+		var w bytes.Buffer
+		item, err := pickCandidate(strings.NewReader(test.answers), &w,
+			ambiguity)
+		got := 0
+		if item != nil {
+			got = item.ID
+		}
+		if got != test.want || (err == nil) != (test.want != 0) {
+			t.Errorf("pickCandidate answered %q: got ID %d, %v; want %d",
+				test.answers, got, err, test.want)
+		}
+		menu := "\"/News/Both\" could be any of:\n" +
+			"  1) CAT:2      category Both\n" +
+			"  2) FEED:12    feed Both\n"
+		prompts := strings.Count(w.String(), "which one? [1-2] ")
+		if !strings.HasPrefix(w.String(), menu) || prompts != test.prompts {
+			t.Errorf("pickCandidate answered %q: asked %q, "+
+				"want the menu and %d prompts", test.answers, &w,
+				test.prompts)
+		}
+	}
+}
+
+func TestResolveCatPathNotFound(t *testing.T) {
+	useStub(t, newStubServer(t, map[string]stubOp{
+		"getFeedTree": prefixTree}))
+
+	tests := []struct {
+		catpath    string
+		failed     string
+		candidates []string
+	}{
+		{"/Nope", "Nope", []string{"News", "a/b"}},
+		{"/News/Nope/Go Blog", "Nope",
+			[]string{"Tech", "Tech", "Travel", "World"}},
+		{"/News/World/Go Blog", "World",
+			[]string{"Tech", "Tech", "Travel", "World"}},
+		{"/News/Tech/Nope", "Nope", []string{"Go Blog"}},
+		{"/News/Travel/Nope", "Nope", nil},
+		{"/News/World/", "World",
+			[]string{"Tech", "Tech", "Travel", "World"}},
+	}
+	for _, test := range tests {
+		_, err := ResolveCatPath(test.catpath)
+		var pathErr *PathError
+		if !errors.As(err, &pathErr) {
+			t.Errorf("ResolveCatPath(%q): got %v, want a PathError",
+				test.catpath, err)
+			continue
+		}
+		if pathErr.Path != test.catpath ||
+			pathErr.FailedComponent != test.failed ||
+			!reflect.DeepEqual(pathErr.Candidates, test.candidates) {
+			t.Errorf("ResolveCatPath(%q): got %+v, want %q failed among %q",
+				test.catpath, pathErr, test.failed, test.candidates)
+		}
+	}
+}
+
+func TestAgeValue(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		text    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"2d", 2 * day, false},
+		{"1w", 7 * day, false},
+		{"0d", 0, false},
+		{"36h", 36 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"d", 0, true},
+		{"-1d", 0, true},
+		{"-3h", 0, true},
+		{"1.5d", 0, true},
+		{"2y", 0, true},
+		{"soon", 0, true},
+	}
+	for _, test := range tests {
+		var age ageValue
+		err := age.Set(test.text)
+		if time.Duration(age) != test.want || (err != nil) != test.wantErr {
+			t.Errorf("ageValue.Set(%q): got %v, %v; want %v, error: %v",
+				test.text, time.Duration(age), err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestConfirmHostPattern(t *testing.T) {
+	okOp := func(map[string]interface{}) interface{} {
+		return map[string]interface{}{"status": "OK"}
+	}
+	commands := []struct {
+		op   string
+		args []string
+	}{
+		{"unsubscribeFeed", []string{"rm", "/News/A"}},
+		{"removeCategory", []string{"rmdir", "/Empty"}},
+		{"catchupFeed", []string{"touch", "/News/A"}},
+	}
+	tests := []struct {
+		name    string
+		pattern string
+		iKnow   bool
+		want    int
+	}{
+		{"matched", `127\.0\.0\.1`, false, EX_NOPERM},
+		{"matched, --i-know", `127\.0\.0\.1`, true, EX_SUCCESS},
+		{"unmatched", `feeds\.example\.com`, false, EX_SUCCESS},
+	}
+	for _, test := range tests {
+		dotfile := filepath.Join(t.TempDir(), "ttrss-tool.json")
+		config := `{"confirm_host_pattern": "` +
+			strings.ReplaceAll(test.pattern, `\`, `\\`) + `"}`
+		if err := os.WriteFile(dotfile, []byte(config), 0600); err != nil {
+			t.Fatal(err)
+		}
+		for _, command := range commands {
+			stub := newStubServer(t, map[string]stubOp{
+				"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "A")),
+					catItem(2, "Empty")),
+				command.op: okOp,
+			})
+			args := []string{"--dotfile", dotfile, command.args[0]}
+			if test.iKnow {
+				args = append(args, "--i-know")
+			}
+			args = append(args, command.args[1:]...)
+			_, stderr, code := runTool(t, stub, "", args...)
+			calls := len(stub.called(command.op))
+			wantCalls := 1
+			if test.want != EX_SUCCESS {
+				wantCalls = 0
+			}
+			if code != test.want || calls != wantCalls {
+				t.Errorf("%s: %q: got exit %d, %d %s calls, stderr %q; "+
+					"want exit %d, %d calls", test.name, command.args, code,
+					calls, command.op, stderr, test.want, wantCalls)
+			}
+		}
+	}
+}
+
+func TestOutputFile(t *testing.T) {
+	tests := [][]string{
+		{"cat", "/News/A"},
+		{"grep", "Go", "/News/A"},
+		{"tree", "/News"},
+	}
+	for _, args := range tests {
+		stub := newStubServer(t, map[string]stubOp{
+			"getFeedTree": treeOp(catItem(1, "News", feedItem(10, "A"))),
+			"getHeadlines": headlinesOp(headlineItem(100, 10, "Go news"),
+				headlineItem(101, 10, "Other news")),
+		})
+		want, stderr, code := runTool(t, stub, "", args...)
+		if code != EX_SUCCESS || want == "" {
+			t.Fatalf("%q: got exit %d, stdout %q, stderr %q", args, code,
+				want, stderr)
+		}
+
+		path := filepath.Join(t.TempDir(), "out")
+		withOutput := append([]string{args[0], "-o", path}, args[1:]...)
+		stdout, stderr, code := runTool(t, stub, "", withOutput...)
+		got, err := os.ReadFile(path)
+		if code != EX_SUCCESS || stdout != "" || err != nil ||
+			string(got) != want {
+			t.Errorf("%q: got exit %d, stdout %q, stderr %q, file %q "+
+				"(%v); want exit 0, no stdout, and file %q", withOutput,
+				code, stdout, stderr, got, err, want)
+		}
+		if info, err := os.Stat(path); err == nil &&
+			info.Mode().Perm()&^0644 != 0 {
+			t.Errorf("%q: file mode %v, want at most 0644", withOutput,
+				info.Mode().Perm())
+		}
+
+		unwritable := filepath.Join(t.TempDir(), "missing", "out")
+		withOutput = append([]string{args[0], "-o", unwritable},
+			args[1:]...)
+		_, stderr, code = runTool(t, stub, "", withOutput...)
+		if code != EX_CANTCREAT || !strings.Contains(stderr, unwritable) {
+			t.Errorf("%q: got exit %d, stderr %q; want exit %d, naming "+
+				"the file", withOutput, code, stderr, EX_CANTCREAT)
+		}
+	}
+}
+
+// Run with -race too: report must only ever see work that is done.
+func TestInParallel(t *testing.T) {
+	const n = 20
+	for _, jobs := range []int{1, 4, n + 1} {
+		var running, most int32
+		finished := make([]bool, n)
+		var order []int
+		work := func(i int) {
+			now := atomic.AddInt32(&running, 1)
+			for {
+				seen := atomic.LoadInt32(&most)
+				if now <= seen ||
+					atomic.CompareAndSwapInt32(&most, seen, now) {
+					break
+				}
+			}
+			// Later work finishes sooner, to tempt reports out of order.
+			time.Sleep(time.Duration(n-i) * time.Millisecond)
+			finished[i] = true
+			atomic.AddInt32(&running, -1)
+		}
+		report := func(i int) {
+			if !finished[i] {
+				t.Errorf("jobs %d: report(%d) before work(%d) was done",
+					jobs, i, i)
+			}
+			order = append(order, i)
+		}
+		inParallel(n, jobs, work, report)
+
+		for i := range order {
+			if order[i] != i {
+				t.Fatalf("jobs %d: reported in order %v", jobs, order)
+			}
+		}
+		if len(order) != n {
+			t.Errorf("jobs %d: reported %d of %d", jobs, len(order), n)
+		}
+		if most > int32(jobs) {
+			t.Errorf("jobs %d: ran %d at a time", jobs, most)
+		}
+		if jobs > 1 && jobs <= n && most < 2 {
+			t.Errorf("jobs %d: never ran more than one at a time", jobs)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// vi: set noet ts=4 sw=4 ft=go tw=79:
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"ttrss"
+)
+
+type URL struct {
+	flHelp bool
+	flags  flag.FlagSet
+}
+
+func (url *URL) Init() {
+	url.flags.Init("url", flag.PanicOnError)
+
+	url.flags.BoolVar(&url.flHelp, "h", false, "help")
+	url.flags.BoolVar(&url.flHelp, "help", false, "help")
+}
+
+func (url *URL) Flags() *flag.FlagSet {
+	return &url.flags
+}
+
+func (url *URL) Synopsis(w io.Writer) {
+	fmt.Fprintln(w, "url catpath... -- print the URLs of feeds")
+}
+
+func (url *URL) Run(args []string) {
+	url.flags.Parse(args)
+
+	if url.flHelp {
+		flagSetPrintUsage(url.flags, os.Stdout, "url")
+		exit(EX_SUCCESS)
+	}
+
+	if url.flags.NArg() < 1 {
+		flagSetPrintUsage(url.flags, os.Stderr, "url")
+		exit(EX_USAGE)
+	}
+
+	// The tree doesn't know feed URLs, so get them all in one go.
+	feeds, err := tt.GetFeeds(ttrss.CATEGORY_FEEDS_NOT_VIRTUAL, false, false)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "url:", err)
+		exit(EX_UNAVAILABLE)
+	}
+	urlByID := make(map[int]string, len(feeds))
+	for _, feed := range feeds {
+		urlByID[feed.ID] = feed.FeedURL
+	}
+
+	code := EX_SUCCESS
+	for _, catpath := range url.flags.Args() {
+		item, err := ResolveCatPath(catpath)
+		if err == nil && item.Type != ttrss.Feed {
+			err = fmt.Errorf("not a feed: %q", catpath)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "url:", err)
+			printCandidates(err)
+			code = EX_DATAERR
+			continue
+		}
+
+		feedURL, ok := urlByID[item.ID]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "url: %q: server gave no URL for FEED:%d\n",
+				catpath, item.ID)
+			code = EX_DATAERR
+			continue
+		}
+		fmt.Println(display(feedURL))
+	}
+	exit(code)
+}